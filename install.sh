@@ -50,7 +50,7 @@ VERSION=$(git describe --tags --always --dirty 2>/dev/null || echo "dev")
 COMMIT_SHA=$(git rev-parse --short HEAD 2>/dev/null || echo "none")
 BUILD_TIME=$(date -u +"%Y-%m-%dT%H:%M:%SZ")
 # Format LDFLAGS properly for go build command
-LDFLAGS=""
+LDFLAGS="-ldflags \"-X github.com/blazity/enterprise-cli/pkg/version.Version=${VERSION} -X github.com/blazity/enterprise-cli/pkg/version.Commit=${COMMIT_SHA} -X github.com/blazity/enterprise-cli/pkg/version.BuildTime=${BUILD_TIME}\""
 
 # Colors for output - Based on the gif aesthetic
 CYAN='\033[0;36m'
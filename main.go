@@ -6,7 +6,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/blazity/enterprise-cli/pkg/enterprise"
 	"github.com/blazity/enterprise-cli/pkg/logging"
@@ -30,15 +29,27 @@ func main() {
 		logger.Info("Operation was cancelled before execution")
 		os.Exit(1)
 	default:
-		if err := cmd.ExecuteContext(GlobalCtx); err != nil {
+		// On a signal the context is cancelled and the command returns once its rollback has finished
+		err := cmd.ExecuteContext(GlobalCtx)
+		if err != nil {
 			logger.Error(err.Error())
+		}
+		// A failed command skips PersistentPostRun, so the log file is closed here as well
+		logger.Close()
+		if GlobalCtx.Err() != nil {
+			logger.Info("Graceful shutdown completed")
+			os.Exit(1)
+		}
+		if err != nil {
 			os.Exit(1)
 		}
 	}
 }
 
+// setupSignalHandling cancels GlobalCtx on the first interrupt, leaving the running command to roll back
+// its changes, and exits immediately on the second
 func setupSignalHandling() {
-	c := make(chan os.Signal, 1)
+	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
@@ -48,23 +59,13 @@ func setupSignalHandling() {
 
 		logger := logging.GetLogger()
 		logger.Info("Received signal: " + sig.String())
-		logger.Info("Cancelling operations...")
+		logger.Info("Cancelling operations and rolling back, press Ctrl+C again to exit immediately...")
 
 		GlobalCancel()
 
-		cleanup := make(chan struct{})
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			close(cleanup)
-		}()
-
-		select {
-		case <-cleanup:
-			logger.Info("Graceful shutdown completed")
-		case <-time.After(2 * time.Second):
-			logger.Warning("Cleanup timed out, forcing exit")
-		}
-
-		os.Exit(1)
+		<-c
+		fmt.Print("\r\033[K")
+		logger.Warning("Exiting without waiting for the rollback to finish")
+		os.Exit(130)
 	}()
 }
@@ -1,7 +1,9 @@
 package codemod
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -9,6 +11,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/runner"
 )
 
 //go:embed codemods/*.js
@@ -67,10 +71,11 @@ func RunJsCodemodFromFlags() error {
 	if err != nil {
 		return err
 	}
-	return RunJsCodemod(cfg)
+	return RunJsCodemod(context.Background(), cfg)
 }
 
-func RunJsCodemod(cfg *JsCodemodConfig) error {
+// RunJsCodemod applies the codemod described by cfg with jscodeshift, which is killed when ctx is done
+func RunJsCodemod(ctx context.Context, cfg *JsCodemodConfig) error {
 	// unpack the embedded codemods into a temp directory
 	tmpDir, err := extractEmbeddedCodemods()
 	if err != nil {
@@ -100,12 +105,9 @@ func RunJsCodemod(cfg *JsCodemodConfig) error {
 		return fmt.Errorf("path validation failed: %w", err)
 	}
 
-	cmd, err := prepareCommand(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to prepare jscodeshift command: %w", err)
-	}
+	cmd := prepareCommand(cfg)
 
-	if err := runCommand(cmd, cfg); err != nil {
+	if err := runCommand(ctx, cmd, cfg); err != nil {
 		return fmt.Errorf("failed to run jscodeshift: %w", err)
 	}
 
@@ -236,12 +238,7 @@ func listAvailableTransforms(jsCodemodAbsPath string) string {
 	return builder.String()
 }
 
-func prepareCommand(cfg *JsCodemodConfig) (*exec.Cmd, error) {
-	npxPath, err := exec.LookPath("npx")
-	if err != nil {
-		return nil, fmt.Errorf("command 'npx' not found in PATH. Is Node.js (which includes npx) installed and configured correctly? (%w)", err)
-	}
-
+func prepareCommand(cfg *JsCodemodConfig) runner.Command {
 	args := []string{
 		"jscodeshift",
 		"-t", cfg.TransformPath,
@@ -258,18 +255,23 @@ func prepareCommand(cfg *JsCodemodConfig) (*exec.Cmd, error) {
 
 	args = append(args, cfg.InputPath)
 
-	cmd := exec.Command(npxPath, args...)
-
-	return cmd, nil
+	return runner.Command{Name: "npx", Args: args}
 }
 
-func runCommand(cmd *exec.Cmd, cfg *JsCodemodConfig) error {
+func runCommand(ctx context.Context, cmd runner.Command, cfg *JsCodemodConfig) error {
 	if cfg.Verbose {
-		fmt.Printf("Executing codemod command: %s\n", strings.Join(cmd.Args, " "))
+		fmt.Printf("Executing codemod command: %s\n", cmd)
 	}
 
-	output, err := cmd.CombinedOutput()
+	result, err := runner.Run(ctx, cmd)
+	output := result.Combined()
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("command 'npx' not found in PATH. Is Node.js (which includes npx) installed and configured correctly? (%w)", err)
+		}
 		return fmt.Errorf("jscodeshift process execution failed: %w\nOutput:\n%s", err, string(output))
 	}
 
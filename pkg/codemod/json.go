@@ -0,0 +1,379 @@
+package codemod
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageManager identifies the Node.js package manager used by a project
+type PackageManager string
+
+const (
+	PackageManagerNpm  PackageManager = "npm"
+	PackageManagerYarn PackageManager = "yarn"
+	PackageManagerPnpm PackageManager = "pnpm"
+	PackageManagerBun  PackageManager = "bun"
+)
+
+// lockFiles maps lockfile names to their package managers, in detection priority order
+var lockFiles = []struct {
+	name    string
+	manager PackageManager
+}{
+	{"pnpm-lock.yaml", PackageManagerPnpm},
+	{"yarn.lock", PackageManagerYarn},
+	{"bun.lockb", PackageManagerBun},
+	{"bun.lock", PackageManagerBun},
+	{"package-lock.json", PackageManagerNpm},
+	{"npm-shrinkwrap.json", PackageManagerNpm},
+}
+
+// DetectPackageManager inspects the lockfiles in dir and returns the package manager in use.
+// It falls back to the "packageManager" field of package.json and finally to npm.
+func DetectPackageManager(dir string) PackageManager {
+	for _, lf := range lockFiles {
+		if _, err := os.Stat(filepath.Join(dir, lf.name)); err == nil {
+			return lf.manager
+		}
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err == nil {
+		var pkg struct {
+			PackageManager string `json:"packageManager"`
+		}
+		if json.Unmarshal(src, &pkg) == nil && pkg.PackageManager != "" {
+			name := strings.SplitN(pkg.PackageManager, "@", 2)[0]
+			switch PackageManager(name) {
+			case PackageManagerNpm, PackageManagerYarn, PackageManagerPnpm, PackageManagerBun:
+				return PackageManager(name)
+			}
+		}
+	}
+
+	return PackageManagerNpm
+}
+
+// PackageJsonCodemodConfig holds configuration for the package.json codemod.
+// Dependencies, DevDependencies and Scripts are added or updated, while Engines
+// are only added when the project does not constrain them already.
+type PackageJsonCodemodConfig struct {
+	Path            string
+	Dependencies    map[string]string
+	DevDependencies map[string]string
+	Scripts         map[string]string
+	Engines         map[string]string
+}
+
+// NewDefaultPackageJsonCodemodConfig returns a default PackageJsonCodemodConfig
+func NewDefaultPackageJsonCodemodConfig() *PackageJsonCodemodConfig {
+	return &PackageJsonCodemodConfig{
+		Path:            "package.json",
+		Dependencies:    map[string]string{},
+		DevDependencies: map[string]string{},
+		Scripts:         map[string]string{},
+		Engines:         map[string]string{},
+	}
+}
+
+// RunPackageJsonCodemod edits package.json in place, preserving key order,
+// indentation, line endings and the trailing newline of the original file
+func RunPackageJsonCodemod(cfg *PackageJsonCodemodConfig) error {
+	src, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", cfg.Path, err)
+	}
+
+	root, err := parseOrderedJson(src)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", cfg.Path, err)
+	}
+	obj, ok := root.(*jsonObject)
+	if !ok {
+		return fmt.Errorf("%s does not contain a JSON object", cfg.Path)
+	}
+
+	setSectionValues(obj, "dependencies", cfg.Dependencies, true)
+	setSectionValues(obj, "devDependencies", cfg.DevDependencies, true)
+	setSectionValues(obj, "scripts", cfg.Scripts, true)
+	setSectionValues(obj, "engines", cfg.Engines, false)
+
+	out := formatOrderedJson(obj, detectJsonIndent(src))
+	if bytes.HasSuffix(src, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	if bytes.Contains(src, []byte("\r\n")) {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	}
+
+	info, err := os.Stat(cfg.Path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", cfg.Path, err)
+	}
+	if err := os.WriteFile(cfg.Path, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing updated %s: %w", cfg.Path, err)
+	}
+	return nil
+}
+
+// setSectionValues writes values into the named object section of obj, creating it when missing.
+// New keys are inserted alphabetically when the section is already sorted, as npm keeps it.
+func setSectionValues(obj *jsonObject, section string, values map[string]string, overwrite bool) {
+	if len(values) == 0 {
+		return
+	}
+
+	sectionObj, ok := obj.Get(section).(*jsonObject)
+	if !ok {
+		sectionObj = newJsonObject()
+		obj.Set(section, sectionObj)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if sectionObj.Has(name) && !overwrite {
+			continue
+		}
+		if !sectionObj.Has(name) && sort.StringsAreSorted(sectionObj.keys) {
+			sectionObj.InsertSorted(name, values[name])
+			continue
+		}
+		sectionObj.Set(name, values[name])
+	}
+}
+
+// jsonObject is a JSON object that remembers the order of its keys
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newJsonObject() *jsonObject {
+	return &jsonObject{values: map[string]interface{}{}}
+}
+
+func (o *jsonObject) Has(key string) bool {
+	_, ok := o.values[key]
+	return ok
+}
+
+func (o *jsonObject) Get(key string) interface{} {
+	return o.values[key]
+}
+
+// Set updates key in place, or appends it when the key is new
+func (o *jsonObject) Set(key string, value interface{}) {
+	if !o.Has(key) {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// InsertSorted adds a new key at its alphabetical position
+func (o *jsonObject) InsertSorted(key string, value interface{}) {
+	idx := sort.SearchStrings(o.keys, key)
+	o.keys = append(o.keys, "")
+	copy(o.keys[idx+1:], o.keys[idx:])
+	o.keys[idx] = key
+	o.values[key] = value
+}
+
+// parseOrderedJson decodes src into jsonObject, []interface{}, string, json.Number, bool or nil values
+func parseOrderedJson(src []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+
+	value, err := decodeOrderedValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return value, nil
+}
+
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := newJsonObject()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyTok.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected object key, got %v", keyTok)
+			}
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+
+	return nil, fmt.Errorf("unexpected delimiter %v", delim)
+}
+
+// detectJsonIndent returns the indentation unit of the first indented line, defaulting to two spaces
+func detectJsonIndent(src []byte) string {
+	for _, line := range strings.Split(string(src), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// formatOrderedJson serializes value the way JSON.stringify(value, null, indent) does
+func formatOrderedJson(value interface{}, indent string) []byte {
+	var buf bytes.Buffer
+	writeOrderedValue(&buf, value, indent, 0)
+	return buf.Bytes()
+}
+
+func writeOrderedValue(buf *bytes.Buffer, value interface{}, indent string, depth int) {
+	switch v := value.(type) {
+	case *jsonObject:
+		if len(v.keys) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{\n")
+		for i, key := range v.keys {
+			buf.WriteString(strings.Repeat(indent, depth+1))
+			writeJsonString(buf, key)
+			buf.WriteString(": ")
+			writeOrderedValue(buf, v.values[key], indent, depth+1)
+			if i < len(v.keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(indent, depth))
+		buf.WriteByte('}')
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteString("[\n")
+		for i, item := range v {
+			buf.WriteString(strings.Repeat(indent, depth+1))
+			writeOrderedValue(buf, item, indent, depth+1)
+			if i < len(v)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(indent, depth))
+		buf.WriteByte(']')
+	case string:
+		writeJsonString(buf, v)
+	case json.Number:
+		buf.WriteString(v.String())
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case nil:
+		buf.WriteString("null")
+	default:
+		data, _ := json.Marshal(v)
+		buf.Write(data)
+	}
+}
+
+func writeJsonString(buf *bytes.Buffer, s string) {
+	var strBuf bytes.Buffer
+	enc := json.NewEncoder(&strBuf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Write(bytes.TrimRight(strBuf.Bytes(), "\n"))
+}
+
+// MergeJson adds the keys of incoming that are missing from existing, recursing into nested objects.
+// Values already present in existing win, and the formatting of existing is preserved.
+func MergeJson(existing, incoming []byte) ([]byte, error) {
+	base, err := parseOrderedJson(existing)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing existing JSON: %w", err)
+	}
+	other, err := parseOrderedJson(incoming)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing incoming JSON: %w", err)
+	}
+
+	baseObj, ok := base.(*jsonObject)
+	otherObj, otherOk := other.(*jsonObject)
+	if !ok || !otherOk {
+		return nil, fmt.Errorf("only JSON objects can be merged")
+	}
+	mergeJsonObjects(baseObj, otherObj)
+
+	out := formatOrderedJson(baseObj, detectJsonIndent(existing))
+	if bytes.HasSuffix(existing, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	if bytes.Contains(existing, []byte("\r\n")) {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	}
+	return out, nil
+}
+
+func mergeJsonObjects(base, other *jsonObject) {
+	for _, key := range other.keys {
+		if !base.Has(key) {
+			base.Set(key, other.values[key])
+			continue
+		}
+		baseChild, ok := base.values[key].(*jsonObject)
+		otherChild, otherOk := other.values[key].(*jsonObject)
+		if ok && otherOk {
+			mergeJsonObjects(baseChild, otherChild)
+		}
+	}
+}
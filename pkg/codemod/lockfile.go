@@ -0,0 +1,93 @@
+package codemod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/runner"
+)
+
+// LockFile returns the name of the lockfile in dir, or an empty string when there is none
+func LockFile(dir string) string {
+	for _, lf := range lockFiles {
+		if _, err := os.Stat(filepath.Join(dir, lf.name)); err == nil {
+			return lf.name
+		}
+	}
+	return ""
+}
+
+// IsYarnBerry reports whether the project in dir uses Yarn 2 or later, whose lockfile format and
+// install flags differ from Yarn 1
+func IsYarnBerry(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".yarnrc.yml")); err == nil {
+		return true
+	}
+
+	if src, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			PackageManager string `json:"packageManager"`
+		}
+		if json.Unmarshal(src, &pkg) == nil && strings.HasPrefix(pkg.PackageManager, "yarn@") {
+			major, _, _ := strings.Cut(strings.TrimPrefix(pkg.PackageManager, "yarn@"), ".")
+			if n, err := strconv.Atoi(major); err == nil {
+				return n >= 2
+			}
+		}
+	}
+
+	// Berry lockfiles are YAML with a metadata entry, Yarn 1 lockfiles are not
+	lock, err := os.ReadFile(filepath.Join(dir, "yarn.lock"))
+	return err == nil && bytes.Contains(lock, []byte("__metadata:"))
+}
+
+// lockfileUpdateCommand returns the command bringing the lockfile of dir in line with package.json.
+// Yarn 1 has no lockfile-only mode, so it installs the dependencies as well
+func lockfileUpdateCommand(dir string, pm PackageManager) runner.Command {
+	cmd := runner.Command{Name: string(pm), Dir: dir}
+	switch pm {
+	case PackageManagerPnpm:
+		cmd.Args = []string{"install", "--lockfile-only", "--ignore-scripts"}
+	case PackageManagerYarn:
+		if IsYarnBerry(dir) {
+			cmd.Args = []string{"install", "--mode=update-lockfile"}
+		} else {
+			cmd.Args = []string{"install", "--ignore-scripts", "--non-interactive"}
+		}
+	case PackageManagerBun:
+		cmd.Args = []string{"install", "--lockfile-only", "--ignore-scripts"}
+	default:
+		cmd.Args = []string{"install", "--package-lock-only", "--ignore-scripts", "--no-audit", "--no-fund"}
+	}
+	return cmd
+}
+
+// UpdateLockfile refreshes the lockfile in dir after package.json was edited, so frozen installs keep
+// working. It returns the lockfile name, or an empty string when dir has no lockfile to refresh
+func UpdateLockfile(ctx context.Context, dir string, pm PackageManager) (string, error) {
+	lockFile := LockFile(dir)
+	if lockFile == "" {
+		return "", nil
+	}
+
+	cmd := lockfileUpdateCommand(dir, pm)
+	result, err := runner.Run(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("command '%s' not found in PATH: %w", pm, err)
+		}
+		return "", fmt.Errorf("%s failed: %w\nOutput:\n%s", cmd, err, string(result.Combined()))
+	}
+	return lockFile, nil
+}
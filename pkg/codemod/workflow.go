@@ -0,0 +1,335 @@
+package codemod
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/layout"
+	"gopkg.in/yaml.v3"
+)
+
+// workflowPathKeys are the GitHub Actions keys whose values, or list items, are repository paths
+var workflowPathKeys = map[string]bool{
+	"working-directory":     true,
+	"paths":                 true,
+	"paths-ignore":          true,
+	"cache-dependency-path": true,
+	"context":               true,
+	"file":                  true,
+	"dockerfile":            true,
+}
+
+// WorkflowCodemodConfig holds configuration for the GitHub Actions workflow codemod.
+// PathRewrites maps the directories the workflows were written for to their new locations.
+type WorkflowCodemodConfig struct {
+	WorkflowsDir string
+	PathRewrites map[string]string
+}
+
+// NewDefaultWorkflowCodemodConfig returns a default WorkflowCodemodConfig
+func NewDefaultWorkflowCodemodConfig() *WorkflowCodemodConfig {
+	return &WorkflowCodemodConfig{
+		WorkflowsDir: filepath.Join(".github", "workflows"),
+		PathRewrites: map[string]string{},
+	}
+}
+
+// RunWorkflowCodemod rewrites the repository paths of every workflow in cfg.WorkflowsDir,
+// editing only the rewritten values so comments and formatting are preserved.
+// It returns the files it changed.
+func RunWorkflowCodemod(cfg *WorkflowCodemodConfig) ([]string, error) {
+	if len(cfg.PathRewrites) == 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(cfg.WorkflowsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", cfg.WorkflowsDir, err)
+	}
+
+	changed := []string{}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		path := filepath.Join(cfg.WorkflowsDir, entry.Name())
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return changed, fmt.Errorf("error reading %s: %w", path, err)
+		}
+
+		out, err := rewriteWorkflowPaths(src, cfg.PathRewrites)
+		if err != nil {
+			return changed, fmt.Errorf("error rewriting %s: %w", path, err)
+		}
+		if string(out) == string(src) {
+			continue
+		}
+
+		if err := os.WriteFile(path, out, 0644); err != nil {
+			return changed, fmt.Errorf("error writing %s: %w", path, err)
+		}
+		changed = append(changed, path)
+	}
+
+	return changed, nil
+}
+
+type scalarEdit struct {
+	line   int
+	column int
+	old    string
+	new    string
+	// raw edits replace a whole line of a block scalar, whose first character may be a quote
+	raw bool
+}
+
+func rewriteWorkflowPaths(src []byte, rewrites map[string]string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+
+	lines := strings.SplitAfter(string(src), "\n")
+
+	var edits []scalarEdit
+	// addEdit rewrites a scalar with rewrite, line by line for literal and folded scalars,
+	// whose content starts below the node and is indented deeper than its key
+	addEdit := func(key, node *yaml.Node, rewrite func(string) string) {
+		if node.Kind != yaml.ScalarNode {
+			return
+		}
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			if rewritten := rewrite(node.Value); rewritten != node.Value {
+				edits = append(edits, scalarEdit{line: node.Line, column: node.Column, old: node.Value, new: rewritten})
+			}
+			return
+		}
+		for n := node.Line; n < len(lines); n++ {
+			line := strings.TrimRight(lines[n], "\r\n")
+			content := strings.TrimLeft(line, " \t")
+			if content == "" {
+				continue
+			}
+			indent := len(line) - len(content)
+			if indent < key.Column {
+				break
+			}
+			if rewritten := rewrite(content); rewritten != content {
+				edits = append(edits, scalarEdit{line: n + 1, column: indent + 1, old: content, new: rewritten, raw: true})
+			}
+		}
+	}
+	rewritePath := func(value string) string {
+		return layout.RewritePath(value, rewrites)
+	}
+	rewriteLines := func(value string) string {
+		return rewritePathLines(value, rewrites)
+	}
+	rewriteTokens := func(value string) string {
+		return rewritePathTokens(value, rewrites)
+	}
+
+	// expected holds the value of every scalar after rewriting, in document order, to check the edits against
+	var expected []string
+	visit := func(key, node *yaml.Node, rewrite func(string) string) {
+		if node.Kind == yaml.ScalarNode {
+			expected = append(expected, rewrite(node.Value))
+			addEdit(key, node, rewrite)
+		}
+	}
+	unchanged := func(value string) string { return value }
+
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				visit(node, child, unchanged)
+				walk(child)
+			}
+		case yaml.SequenceNode:
+			for _, child := range node.Content {
+				visit(node, child, rewriteTokens)
+				walk(child)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				visit(key, key, unchanged)
+				switch {
+				case workflowPathKeys[key.Value]:
+					visit(key, value, rewritePath)
+					if value.Kind == yaml.SequenceNode {
+						for _, item := range value.Content {
+							visit(key, item, rewritePath)
+						}
+						continue
+					}
+				case key.Value == "path":
+					// with.path of actions such as cache and upload-artifact holds one path per line
+					visit(key, value, rewriteLines)
+				default:
+					// run scripts, hashFiles() expressions and any other value mentioning a template path
+					visit(key, value, rewriteTokens)
+				}
+				walk(value)
+			}
+		}
+	}
+	walk(&doc)
+
+	if len(edits) == 0 {
+		return src, nil
+	}
+
+	// Apply from the end of the file, so earlier positions stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line > edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+
+	for _, edit := range edits {
+		line := lines[edit.line-1]
+		start := edit.column - 1
+		if !edit.raw && start < len(line) && (line[start] == '"' || line[start] == '\'') {
+			start++
+		}
+		if start > len(line) || !strings.HasPrefix(line[start:], edit.old) {
+			// Columns count runes, fall back to searching when the line is not plain ASCII
+			if strings.Count(line, edit.old) != 1 {
+				return nil, fmt.Errorf("line %d: could not locate %q", edit.line, edit.old)
+			}
+			start = strings.Index(line, edit.old)
+		}
+		lines[edit.line-1] = line[:start] + edit.new + line[start+len(edit.old):]
+	}
+
+	out := []byte(strings.Join(lines, ""))
+	if err := checkWorkflowPaths(out, expected); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// checkWorkflowPaths fails when a rewritten workflow does not hold the expected scalar values,
+// so a value the codemod could not edit in place is reported instead of breaking the pipeline later
+func checkWorkflowPaths(src []byte, expected []string) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return fmt.Errorf("rewritten workflow is not valid YAML: %w", err)
+	}
+
+	var mismatches []string
+	i := 0
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode {
+			if i >= len(expected) || node.Value != expected[i] {
+				mismatches = append(mismatches, fmt.Sprintf("line %d", node.Line))
+			}
+			i++
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(&doc)
+
+	if i != len(expected) {
+		return fmt.Errorf("could not rewrite the template paths, update them by hand")
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("could not rewrite the template paths at %s, update them by hand", strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// rewritePathLines applies layout.RewritePath to every line of a multi-line value
+func rewritePathLines(value string, rewrites map[string]string) string {
+	lines := strings.Split(value, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		lines[i] = strings.Replace(line, trimmed, layout.RewritePath(trimmed, rewrites), 1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rewritePathTokens rewrites the template directories mentioned as paths inside free text such as
+// run scripts and hashFiles() expressions. A directory counts as a path when it is followed by a
+// slash, written as "./dir", or used as the argument of cd or of an option such as -chdir=dir,
+// so a command sharing its name, like terraform, is left alone.
+func rewritePathTokens(value string, rewrites map[string]string) string {
+	from := make([]string, 0, len(rewrites))
+	for dir := range rewrites {
+		from = append(from, dir)
+	}
+	// Longest first, so nested directories win over their parents
+	sort.Slice(from, func(i, j int) bool { return len(from[i]) > len(from[j]) })
+
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		if i > 0 && !strings.ContainsRune(pathTokenBoundaries, rune(value[i-1])) {
+			b.WriteByte(value[i])
+			i++
+			continue
+		}
+
+		start := i
+		dotSlash := strings.HasPrefix(value[i:], "./")
+		if dotSlash {
+			start += 2
+		}
+
+		matched := false
+		for _, dir := range from {
+			if !strings.HasPrefix(value[start:], dir) {
+				continue
+			}
+			end := start + len(dir)
+			if !isPathToken(value, i, end, dotSlash) {
+				continue
+			}
+			b.WriteString(value[i:start])
+			b.WriteString(rewrites[dir])
+			i = end
+			matched = true
+			break
+		}
+		if !matched {
+			b.WriteByte(value[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// pathTokenBoundaries are the characters a path may follow in a script or expression
+const pathTokenBoundaries = " \t\n'\"=(,:;![{"
+
+func isPathToken(value string, start, end int, dotSlash bool) bool {
+	if end < len(value) && value[end] == '/' {
+		return true
+	}
+	if end < len(value) && !strings.ContainsRune(" \t\n'\"),;]}", rune(value[end])) {
+		return false
+	}
+	if dotSlash {
+		return true
+	}
+	before := value[:start]
+	return strings.HasSuffix(before, "=") || strings.HasSuffix(before, "cd ")
+}
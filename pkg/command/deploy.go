@@ -0,0 +1,94 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/diagnose"
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+func NewDeployCommand(ctx context.Context) *cobra.Command {
+	var opts provider.DeployOptions
+	var output *resultOutput
+
+	cmd := &cobra.Command{
+		Use:           "deploy",
+		Short:         "Deploy the prepared repository",
+		Long:          "Trigger the deploy workflow of the repository recorded in " + manifest.FileName,
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			p, _, err := manifestProvider()
+			if err != nil {
+				logger.Error(err.Error())
+				output.Print(cmd, nil, err)
+				return err
+			}
+
+			err = p.DeployWithOptions(cmd.Context(), opts)
+			if err != nil {
+				logger.Error("Failed to deploy: " + err.Error())
+
+				var runErr *provider.RunFailedError
+				if errors.As(err, &runErr) && runErr.Run.Conclusion == "failure" {
+					printDiagnosis(cmd.Context(), output.Human(), runErr.Repository, runErr.Run)
+				}
+			}
+			output.Print(cmd, deployResult(p), err)
+			return err
+		},
+	}
+
+	output = addOutputFlag(cmd)
+	cmd.Flags().StringVar(&opts.Workflow, "workflow", "", "Workflow file to dispatch (defaults to the one recorded in "+manifest.FileName+")")
+	cmd.Flags().StringVar(&opts.Ref, "ref", "", "Branch or tag to deploy (defaults to the recorded branch)")
+	cmd.Flags().BoolVar(&opts.Wait, "wait", false, "Wait for the workflow run to complete")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 30*time.Minute, "Maximum time to wait with --wait")
+
+	return cmd
+}
+
+// deployResult returns the result of the deployment by p, if its provider reports one
+func deployResult(p provider.Provider) interface{} {
+	if reporter, ok := p.(provider.Reporter); ok {
+		if result := reporter.DeployResult(); result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+// manifestProvider reads the manifest of the current repository and returns the provider it was prepared with
+func manifestProvider() (provider.Provider, *manifest.Manifest, error) {
+	m, err := manifest.Read(".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, exists := provider.Get(m.Provider)
+	if !exists {
+		return nil, m, fmt.Errorf("provider not supported: %s", m.Provider)
+	}
+	return p, m, nil
+}
+
+// printDiagnosis explains the failure of run to w from its logs, if they can be downloaded
+func printDiagnosis(ctx context.Context, w io.Writer, repo string, run *github.WorkflowRun) {
+	d, err := diagnose.Diagnose(ctx, repo, run)
+	if err != nil {
+		logging.GetLogger().Warning("Could not download the logs of the failed run", "error", err)
+		return
+	}
+	fmt.Fprint(w, d.Render())
+}
@@ -0,0 +1,120 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/ui"
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+)
+
+func NewDestroyCommand(ctx context.Context) *cobra.Command {
+	var opts provider.DestroyOptions
+	var yes bool
+	var output *resultOutput
+
+	cmd := &cobra.Command{
+		Use:   "destroy",
+		Short: "Tear down the infrastructure and the repository of a prepared project",
+		Long: "Destroy the cloud resources deployed from the repository recorded in " + manifest.FileName + ", delete the bucket " +
+			"holding their state, the GitHub Environments prepare created and the repository itself. The local checkout is kept.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			p, m, err := manifestProvider()
+			if err != nil {
+				logger.Error(err.Error())
+				output.Print(cmd, nil, err)
+				return err
+			}
+			destroyer, ok := p.(provider.Destroyer)
+			if !ok {
+				err := fmt.Errorf("provider does not support destroy: %s", m.Provider)
+				logger.Error(err.Error())
+				output.Print(cmd, nil, err)
+				return err
+			}
+
+			if !yes {
+				if err := confirmDestroy(m, opts); err != nil {
+					logger.Error(err.Error())
+					output.Print(cmd, nil, err)
+					return err
+				}
+			}
+
+			summary, err := destroyer.DestroyWithOptions(cmd.Context(), opts)
+			if err != nil {
+				logger.Error("Failed to destroy: " + err.Error())
+			} else {
+				logger.Info(ui.Success("Destroyed the project"), "repo", m.Repository)
+			}
+			output.Print(cmd, summary, err)
+			return err
+		},
+	}
+
+	output = addOutputFlag(cmd)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation, required when not running in a terminal")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Local credentials profile the infrastructure is destroyed with")
+	cmd.Flags().BoolVar(&opts.KeepInfrastructure, "keep-infrastructure", false, "Keep the cloud resources and the bucket holding their state")
+	cmd.Flags().BoolVar(&opts.KeepRepository, "keep-repository", false, "Keep the GitHub repository, only deleting the environments prepare created")
+
+	return cmd
+}
+
+// confirmDestroy asks the user to type the name of the repository, or of the bucket when the repository is kept
+func confirmDestroy(m *manifest.Manifest, opts provider.DestroyOptions) error {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return errors.New("destroy needs a confirmation, pass --yes when not running in a terminal")
+	}
+
+	expected := m.Repository
+	if opts.KeepRepository || expected == "" {
+		expected = m.Bucket
+	}
+	if expected == "" {
+		expected = "destroy"
+	}
+
+	var answer string
+	input := huh.NewInput().
+		Title("Type " + expected + " to confirm").
+		Description(destroyDescription(m, opts)).
+		Value(&answer)
+	if err := ui.RunForm(huh.NewForm(huh.NewGroup(input)), nil); err != nil {
+		return err
+	}
+	if answer != expected {
+		return errors.New("confirmation did not match, nothing was destroyed")
+	}
+	return nil
+}
+
+// destroyDescription lists what destroy removes with opts
+func destroyDescription(m *manifest.Manifest, opts provider.DestroyOptions) string {
+	var targets []string
+	if !opts.KeepInfrastructure {
+		targets = append(targets, "the deployed infrastructure")
+		if m.Bucket != "" {
+			targets = append(targets, "the state bucket "+m.Bucket)
+		}
+	}
+	if len(m.Environments) > 0 {
+		targets = append(targets, "the environments "+strings.Join(m.Environments, ", "))
+	}
+	if !opts.KeepRepository && m.Repository != "" {
+		targets = append(targets, "the repository "+m.Repository)
+	}
+	return "This permanently deletes " + strings.Join(targets, "; ")
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/blazity/enterprise-cli/pkg/codemod"
+	"github.com/blazity/enterprise-cli/pkg/generate"
+	"github.com/blazity/enterprise-cli/pkg/layout"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/spf13/cobra"
+)
+
+func NewGenerateCommand(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate supporting files for the prepared repository",
+		Long:  "Generate supporting files, such as container image definitions, for the prepared repository",
+	}
+
+	cmd.AddCommand(newGenerateDockerCommand(ctx))
+
+	return cmd
+}
+
+func newGenerateDockerCommand(ctx context.Context) *cobra.Command {
+	var appDir string
+	var packageManager string
+	var nodeVersion string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "docker",
+		Short: "Generate a production Dockerfile and .dockerignore",
+		Long:  "Generate a multi-stage Dockerfile and .dockerignore for the Next.js standalone build",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := logging.GetLogger()
+
+			if appDir == "" {
+				appDir = defaultAppDir()
+			}
+
+			cfg := generate.NewDefaultDockerConfig()
+			cfg.AppDir = appDir
+			cfg.BuildContext = filepath.ToSlash(filepath.Clean(appDir))
+			cfg.WorkspaceDir = workspaceAppDir(cfg.BuildContext)
+			cfg.PackageManager = codemod.PackageManager(packageManager)
+			cfg.NodeVersion = nodeVersion
+			cfg.Force = force
+
+			written, err := generate.GenerateDocker(cfg)
+			if err != nil {
+				logger.Error("Failed to generate Docker files: " + err.Error())
+				return
+			}
+
+			if len(written) == 0 {
+				logger.Info("Nothing was generated, use --force to overwrite existing files")
+				return
+			}
+
+			logger.Info("Generated Docker files", "files", written, "packageManager", cfg.PackageManager, "node", cfg.NodeVersion)
+		},
+	}
+
+	cmd.Flags().StringVar(&appDir, "dir", "", "Directory of the Next.js application (defaults to the one recorded in "+manifest.FileName+", or frontend/ when present)")
+	cmd.Flags().StringVar(&packageManager, "package-manager", "", "Package manager to use (npm, yarn, pnpm, bun); detected from lockfiles by default")
+	cmd.Flags().StringVar(&nodeVersion, "node-version", "", "Node.js image version; detected from .nvmrc or engines by default")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing Dockerfile and .dockerignore")
+
+	return cmd
+}
+
+// workspaceAppDir returns appDir when the manifest records it as the application of a workspace
+func workspaceAppDir(appDir string) string {
+	if m, err := manifest.Read("."); err == nil {
+		if l, err := m.RepositoryLayout(); err == nil && l.Workspace != layout.WorkspaceNone && l.AppDir == appDir {
+			return appDir
+		}
+	}
+	return ""
+}
+
+// defaultAppDir returns the application directory recorded in the manifest, falling back to
+// the template layout when present and to the current directory otherwise
+func defaultAppDir() string {
+	if m, err := manifest.Read("."); err == nil {
+		if l, err := m.RepositoryLayout(); err == nil {
+			return l.AppDir
+		}
+	}
+	if _, err := os.Stat(filepath.Join(layout.TemplateAppDir, "package.json")); err == nil {
+		return layout.TemplateAppDir
+	}
+	return "."
+}
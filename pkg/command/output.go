@@ -0,0 +1,131 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat is the encoding of the structured result printed by --output
+type outputFormat string
+
+const (
+	outputNone outputFormat = ""
+	outputJSON outputFormat = "json"
+	outputYAML outputFormat = "yaml"
+)
+
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch outputFormat(s) {
+	case outputNone, outputJSON, outputYAML:
+		return outputFormat(s), nil
+	}
+	return "", fmt.Errorf("invalid output format %q, expected json or yaml", s)
+}
+
+// commandResult is the structured result of a command. Result holds what is specific to the command
+type commandResult struct {
+	Command    string      `json:"command" yaml:"command"`
+	RunID      string      `json:"runId" yaml:"runId"`
+	Status     string      `json:"status" yaml:"status"`
+	Error      string      `json:"error,omitempty" yaml:"error,omitempty"`
+	DurationMS int64       `json:"durationMs" yaml:"durationMs"`
+	Warnings   []string    `json:"warnings" yaml:"warnings"`
+	Result     interface{} `json:"result,omitempty" yaml:"result,omitempty"`
+}
+
+// resultOutput prints the result of a command to stdout in the format chosen with --output, while logs
+// stay on stderr so the result can be piped to other tools
+type resultOutput struct {
+	format  outputFormat
+	started time.Time
+}
+
+// addOutputFlag adds the --output flag to cmd and returns the output it configures
+func addOutputFlag(cmd *cobra.Command) *resultOutput {
+	o := &resultOutput{started: time.Now()}
+	cmd.Flags().Var((*outputFlag)(&o.format), "output", "Print a structured result to stdout: json or yaml")
+	return o
+}
+
+// Enabled reports whether a structured result was requested, in which case human-readable output
+// belongs on stderr
+func (o *resultOutput) Enabled() bool {
+	return o.format != outputNone
+}
+
+// Human returns where human-readable output is printed
+func (o *resultOutput) Human() io.Writer {
+	if o.Enabled() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// Print writes the result of cmd, which failed when err is not nil. It does nothing without --output
+func (o *resultOutput) Print(cmd *cobra.Command, result interface{}, err error) {
+	if !o.Enabled() {
+		return
+	}
+
+	r := commandResult{
+		Command:    cmd.CommandPath(),
+		RunID:      logging.GetLogger().RunID(),
+		Status:     "succeeded",
+		DurationMS: time.Since(o.started).Milliseconds(),
+		Warnings:   logging.GetLogger().Warnings(),
+		Result:     result,
+	}
+	if r.Warnings == nil {
+		r.Warnings = []string{}
+	}
+	if err != nil {
+		r.Status = "failed"
+		if errors.Is(err, context.Canceled) || cmd.Context().Err() != nil {
+			r.Status = "cancelled"
+		}
+		r.Error = logging.Redact(err.Error())
+	}
+
+	var content []byte
+	var encodeErr error
+	if o.format == outputYAML {
+		content, encodeErr = yaml.Marshal(r)
+	} else {
+		content, encodeErr = json.MarshalIndent(r, "", "  ")
+		content = append(content, '\n')
+	}
+	if encodeErr != nil {
+		logging.GetLogger().Error("Failed to encode the result", "error", encodeErr)
+		return
+	}
+	os.Stdout.Write(content)
+}
+
+// outputFlag validates the value of --output
+type outputFlag outputFormat
+
+func (f *outputFlag) String() string {
+	return string(*f)
+}
+
+func (f *outputFlag) Set(s string) error {
+	format, err := parseOutputFormat(s)
+	if err != nil {
+		return err
+	}
+	*f = outputFlag(format)
+	return nil
+}
+
+func (f *outputFlag) Type() string {
+	return "format"
+}
@@ -2,15 +2,25 @@ package command
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strings"
+	"sync"
 
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/layout"
 	"github.com/blazity/enterprise-cli/pkg/logging"
 	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/secrets"
+	"github.com/blazity/enterprise-cli/pkg/templates"
 	"github.com/blazity/enterprise-cli/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
 func NewPrepareCommand(ctx context.Context) *cobra.Command {
+	var opts provider.PrepareOptions
+	var output *resultOutput
+
 	cmd := &cobra.Command{
 		Use:   "prepare [provider]",
 		Short: "Prepare infrastructure for enterprise deployment",
@@ -35,25 +45,37 @@ func NewPrepareCommand(ctx context.Context) *cobra.Command {
 				availableProviders := provider.ListAvailableProviders()
 				logger.Error("Provider not supported: " + providerName)
 				logger.Info("Available providers: " + strings.Join(availableProviders, ", "))
+				output.Print(cmd, nil, fmt.Errorf("provider not supported: %s", providerName))
 				return
 			}
 
+			progress, stopProgress := startPrepareProgress(cmd, p, opts, output)
+
 			done := make(chan struct{})
 			var prepErr error
 
 			go func() {
-				prepErr = p.Prepare()
+				prepErr = p.PrepareWithOptions(cmdCtx, opts)
 				close(done)
 			}()
 
+			defer func() {
+				output.Print(cmd, prepareResult(p), prepErr)
+			}()
+
 			select {
 			case <-done:
+				stopProgress(prepErr == nil)
 				if prepErr != nil {
 					logger.Error("Failed to prepare: " + prepErr.Error())
 					return
 				}
 				logger.Info("Preparation completed successfully")
+				if result, ok := prepareResult(p).(*provider.PrepareResult); ok {
+					fmt.Fprint(output.Human(), prepareSummary(cmd, result).Render(progress.Plain()))
+				}
 			case <-cmdCtx.Done():
+				stopProgress(false)
 				logger.Info("Preparation cancelled, cleaning up...")
 				<-done
 				if prepErr != nil && prepErr.Error() != "operation cancelled by user" {
@@ -64,5 +86,123 @@ func NewPrepareCommand(ctx context.Context) *cobra.Command {
 		},
 	}
 
+	output = addOutputFlag(cmd)
+	cmd.Flags().StringVar(&opts.Template, "template", "", "Template source: owner/repo[@ref], a local directory or a .tar.gz archive (default \""+templates.DefaultRepository+"\")")
+
+	cmd.Flags().StringVar(&opts.TemplateCommit, "template-commit", "", "Fail unless the template resolves to this commit")
+	cmd.Flags().StringVar(&opts.TemplateSHA256, "template-sha256", "", "Fail unless the template content matches this SHA-256 checksum")
+
+	cmd.Flags().StringVar(&opts.AnswersFile, "answers", "", "YAML file answering the configuration and AWS credential prompts, for non-interactive runs")
+
+	cmd.Flags().StringVar(&opts.UntrackedFiles, "untracked-files", "leave", "What to do with untracked files when moving the application: leave, move, or move-all to include ignored files")
+
+	cmd.Flags().StringVar(&opts.BaseBranch, "base-branch", "", "Branch to prepare from and push to (defaults to the repository's default branch)")
+	cmd.Flags().StringVar(&opts.DirtyTree, "dirty-tree", string(provider.DirtyTreePrompt), "What to do with uncommitted changes: prompt, abort, stash, or worktree to work in a temporary git worktree")
+	cmd.Flags().StringVar(&opts.AppDir, "app-dir", layout.TemplateAppDir, "Directory the Next.js application is moved into, e.g. apps/web")
+	cmd.Flags().StringVar(&opts.InfraDir, "infra-dir", layout.TemplateInfraDir, "Directory the Terraform files are placed in, e.g. infra/terraform")
+	cmd.Flags().StringVar(&opts.Workspace, "workspace", string(layout.WorkspaceNone), "Set up a workspace root package.json: none, npm (also for yarn and bun), pnpm or turbo")
+	cmd.Flags().BoolVar(&opts.Commit.Sign, "sign", false, "Sign the created commits using the signing key from the git configuration")
+	cmd.Flags().StringVar(&opts.Commit.SigningFormat, "signing-format", "", "Signature format for the created commits: openpgp, ssh or x509 (implies --sign)")
+	cmd.Flags().StringVar(&opts.Commit.SigningKey, "signing-key", "", "Key used to sign the created commits (implies --sign)")
+	cmd.Flags().StringVar(&opts.Commit.Author, "author", "", "Author and committer identity of the created commits, as \"Name <email>\"")
+	cmd.Flags().StringVar(&opts.Commit.MessageTemplate, "commit-template", "", "Go template for commit messages with .Step, .Provider, .Ticket and .Message (default \""+github.DefaultCommitTemplate+"\")")
+	cmd.Flags().StringVar(&opts.Commit.Ticket, "ticket", "", "Ticket reference prefixed to commit messages, e.g. OPS-123")
+	cmd.Flags().BoolVar(&opts.Commit.Squash, "squash", false, "Create a single commit for the whole preparation")
+	cmd.Flags().StringVar(&opts.SecretsScope, "secrets-scope", string(secrets.ScopeRepository), "Where secrets and variables are stored: repo, environment, or org to share them from the organization")
+	cmd.Flags().StringVar(&opts.SecretsEnvironment, "secrets-environment", "production", "Environment secrets and variables are stored in with --secrets-scope environment")
+	cmd.Flags().BoolVar(&opts.Harden, "harden", true, "Apply branch protection, environments, CODEOWNERS and team access to the created repository")
+	cmd.Flags().BoolVar(&opts.Hardening.ProtectBranch, "protect-branch", true, "Protect the default branch against force pushes and require checks and reviews")
+	cmd.Flags().StringSliceVar(&opts.Hardening.RequiredChecks, "required-checks", nil, "Checks required before merging into the default branch (defaults to the pull request jobs of the copied workflows)")
+	cmd.Flags().IntVar(&opts.Hardening.RequiredReviews, "required-reviews", 1, "Approving reviews required before merging into the default branch, 0 to disable")
+	cmd.Flags().StringSliceVar(&opts.Hardening.Environments, "environments", []string{"production"}, "GitHub Environments to create")
+	cmd.Flags().StringSliceVar(&opts.Hardening.ProtectedEnvironments, "protected-environments", []string{"production"}, "Environments whose deployments require a review")
+	cmd.Flags().StringSliceVar(&opts.Hardening.EnvironmentReviewers, "environment-reviewers", nil, "Users or org/team slugs reviewing protected deployments (defaults to the current GitHub user)")
+	cmd.Flags().StringSliceVar(&opts.Hardening.CodeOwners, "codeowners", nil, "Owners of the infrastructure and workflows in CODEOWNERS, e.g. @org/platform (defaults to the current GitHub user)")
+	cmd.Flags().StringSliceVar(&opts.Hardening.Teams, "team", nil, "Grant a team access to the repository, as org/team[:permission] with pull, triage, push, maintain or admin")
+	cmd.Flags().StringSliceVar(&opts.KeepAtRoot, "keep-at-root", nil, "Additional top-level files or directories to keep at the repository root")
+
 	return cmd
 }
+
+// startPrepareProgress shows the progress of the steps of p, as a live view on a terminal and as plain lines
+// elsewhere, and returns the function stopping it. Log lines are limited to warnings and errors while the
+// live view is shown, since the steps tell what happens
+func startPrepareProgress(cmd *cobra.Command, p provider.Provider, opts provider.PrepareOptions, output *resultOutput) (*ui.Progress, func(succeeded bool)) {
+	logger := logging.GetLogger()
+	w := output.Human()
+
+	var planned []string
+	if planner, ok := p.(provider.StepPlanner); ok {
+		planned = planner.PrepareSteps(opts)
+	}
+
+	plain := !ui.IsTerminal(w) || logger.IsVerbose()
+	if f := cmd.Flag("log-format"); f != nil && f.Value.String() == string(logging.FormatJSON) {
+		plain = true
+	}
+
+	progress := ui.NewProgress("Preparing "+ui.LegibleProviderName(p.GetName()), w, planned, plain)
+	logging.SetStepObserver(progress)
+	if !plain {
+		logger.SetQuiet(true)
+		logger.SetOutput(progress.Writer(os.Stderr))
+	}
+	progress.Start()
+
+	var once sync.Once
+	return progress, func(succeeded bool) {
+		once.Do(func() {
+			progress.Stop(succeeded)
+			logging.SetStepObserver(nil)
+			if !plain {
+				logger.SetOutput(os.Stderr)
+				logger.SetQuiet(false)
+			}
+		})
+	}
+}
+
+// prepareSummary describes the prepared repository and what is left to do
+func prepareSummary(cmd *cobra.Command, result *provider.PrepareResult) ui.Summary {
+	template := result.Template.Source
+	if result.Template.Commit != "" {
+		template += " @ " + shortCommit(result.Template.Commit)
+	}
+
+	cli := cmd.Root().Name()
+	nextSteps := append([]string{}, result.NextSteps...)
+	nextSteps = append(nextSteps,
+		fmt.Sprintf("Run `%s deploy --wait` to trigger the deploy workflow and follow its run", cli),
+		fmt.Sprintf("Run `%s status` to see what was prepared and the latest workflow run", cli),
+	)
+
+	return ui.Summary{
+		Title: "Repository prepared",
+		Fields: []ui.SummaryField{
+			{Label: "Repository", Value: result.RepositoryURL},
+			{Label: "Branch", Value: result.Branch},
+			{Label: "Template", Value: template},
+			{Label: "Commits", Value: fmt.Sprint(len(result.Commits))},
+		},
+		NextSteps: nextSteps,
+		Warnings:  logging.GetLogger().Warnings(),
+	}
+}
+
+// shortCommit abbreviates a commit SHA like git does
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// prepareResult returns the result of the preparation by p, if its provider reports one
+func prepareResult(p provider.Provider) interface{} {
+	if reporter, ok := p.(provider.Reporter); ok {
+		if result := reporter.PrepareResult(); result != nil {
+			return result
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/blazity/enterprise-cli/pkg/diagnose"
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/ui"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+func NewRunsCommand(ctx context.Context) *cobra.Command {
+	var branch string
+
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Show the workflow runs of the prepared repository",
+		Long:  "List and watch the GitHub Actions workflow runs of the repository recorded in " + manifest.FileName,
+	}
+
+	cmd.PersistentFlags().StringVar(&branch, "branch", "", "Only show runs of this branch")
+
+	cmd.AddCommand(newRunsListCommand(ctx, &branch))
+	cmd.AddCommand(newRunsWatchCommand(ctx, &branch))
+	cmd.AddCommand(newRunsDiagnoseCommand(ctx, &branch))
+
+	return cmd
+}
+
+func newRunsListCommand(ctx context.Context, branch *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "list",
+		Short:         "List recent workflow runs",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := manifest.Read(".")
+			if err != nil {
+				logging.GetLogger().Error(err.Error())
+				return err
+			}
+			return printRuns(cmd.Context(), m.Repository, *branch)
+		},
+	}
+
+	return cmd
+}
+
+func newRunsWatchCommand(ctx context.Context, branch *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch workflow runs live",
+		Long: "Watch the workflow runs with their jobs and steps, refreshing while runs are active. " +
+			"Runs can be cancelled, their failed jobs re-run and their logs viewed from the dashboard",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			m, err := manifest.Read(".")
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+				return printRuns(cmd.Context(), m.Repository, *branch)
+			}
+
+			// Log lines would tear the full screen view
+			logger.SetOutput(io.Discard)
+			defer logger.SetOutput(os.Stderr)
+
+			backend := &runsBackend{ctx: cmd.Context(), repo: m.Repository, branch: *branch}
+			program := tea.NewProgram(ui.NewRunsModel(backend), tea.WithAltScreen(), tea.WithContext(cmd.Context()))
+			if _, err := program.Run(); err != nil && cmd.Context().Err() == nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newRunsDiagnoseCommand(ctx context.Context, branch *string) *cobra.Command {
+	var logFile string
+
+	cmd := &cobra.Command{
+		Use:   "diagnose [run-id]",
+		Short: "Explain why a workflow run failed",
+		Long: "Download the logs of the failed jobs of a run, defaulting to the latest failed one, " +
+			"and match their errors against known Terraform and AWS problems",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			if logFile != "" {
+				content, err := os.ReadFile(logFile)
+				if err != nil {
+					logger.Error(err.Error())
+					return err
+				}
+				fmt.Print(diagnose.Analyze(string(content)).Render())
+				return nil
+			}
+
+			m, err := manifest.Read(".")
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			run, err := failedRun(cmd.Context(), m.Repository, *branch, args)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			printDiagnosis(cmd.Context(), os.Stdout, m.Repository, run)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&logFile, "log-file", "", "Analyse a saved workflow log instead of downloading one")
+
+	return cmd
+}
+
+// failedRun returns the run named in args, or the latest failed run
+func failedRun(ctx context.Context, repo string, branch string, args []string) (*github.WorkflowRun, error) {
+	if len(args) == 1 {
+		return github.GetWorkflowRunByID(ctx, args[0], repo)
+	}
+
+	runs, err := github.GetWorkflowRuns(ctx, repo, branch)
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.Status == "completed" && run.Conclusion == "failure" {
+			return &run, nil
+		}
+	}
+	return nil, fmt.Errorf("no failed workflow runs found in %s", repo)
+}
+
+func printRuns(ctx context.Context, repo string, branch string) error {
+	runs, err := github.GetWorkflowRuns(ctx, repo, branch)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(ui.SubHeader("Workflow runs of " + repo))
+	if len(runs) == 0 {
+		fmt.Println("  No workflow runs yet")
+	}
+	for _, run := range runs {
+		state := run.Conclusion
+		if run.Status != "completed" {
+			state = run.Status
+		}
+		fmt.Printf("  %s %-12s %-24s %-16s %-12s %s\n", ui.RunIcon(run.Status, run.Conclusion), run.ID, run.Name, run.Branch, state, run.URL)
+	}
+	return nil
+}
+
+// runsBackend serves the runs dashboard from the GitHub CLI
+type runsBackend struct {
+	ctx    context.Context
+	repo   string
+	branch string
+}
+
+func (b *runsBackend) Repository() string {
+	return b.repo
+}
+
+func (b *runsBackend) Runs() ([]github.WorkflowRun, error) {
+	return github.GetWorkflowRuns(b.ctx, b.repo, b.branch)
+}
+
+func (b *runsBackend) Jobs(runID string) ([]github.WorkflowJob, error) {
+	return github.GetWorkflowRunJobs(b.ctx, runID, b.repo)
+}
+
+func (b *runsBackend) Cancel(runID string) error {
+	return github.CancelWorkflowRun(b.ctx, runID, b.repo)
+}
+
+func (b *runsBackend) RerunFailed(runID string) error {
+	return github.RerunFailedJobs(b.ctx, runID, b.repo)
+}
+
+func (b *runsBackend) Logs(run github.WorkflowRun) (string, error) {
+	if run.Status == "completed" && run.Conclusion == "failure" {
+		return github.GetFailedWorkflowRunLogs(b.ctx, run.ID, b.repo)
+	}
+	return github.GetWorkflowRunLogs(b.ctx, run.ID, b.repo)
+}
+
+func (b *runsBackend) Diagnose(run github.WorkflowRun) (string, error) {
+	d, err := diagnose.Diagnose(b.ctx, b.repo, &run)
+	if err != nil {
+		return "", err
+	}
+	return d.Render(), nil
+}
+
+func (b *runsBackend) Open(runID string) error {
+	return github.OpenWorkflowRun(b.ctx, runID, b.repo)
+}
+
+func (b *runsBackend) RateLimit() (github.RateLimit, error) {
+	return github.GetRateLimit(b.ctx)
+}
@@ -0,0 +1,353 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/secrets"
+	"github.com/blazity/enterprise-cli/pkg/ui"
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+)
+
+// secretsTargetFlags override where the recorded secrets and variables live
+type secretsTargetFlags struct {
+	scope       string
+	environment string
+}
+
+func NewSecretsCommand(ctx context.Context) *cobra.Command {
+	var target secretsTargetFlags
+
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage the GitHub secrets and variables of the prepared repository",
+		Long:  "List, set, rotate and sync the GitHub secrets and variables the deployment workflows rely on. Secret values are never printed",
+	}
+
+	cmd.PersistentFlags().StringVar(&target.scope, "scope", "", "Scope to work on: repo, environment or org (defaults to the one recorded in "+manifest.FileName+")")
+	cmd.PersistentFlags().StringVar(&target.environment, "environment", "", "Environment to work on with --scope environment")
+
+	cmd.AddCommand(newSecretsListCommand(ctx, &target))
+	cmd.AddCommand(newSecretsSetCommand(ctx, &target))
+	cmd.AddCommand(newSecretsRotateCommand(ctx, &target))
+	cmd.AddCommand(newSecretsSyncCommand(ctx, &target))
+
+	return cmd
+}
+
+func newSecretsListCommand(ctx context.Context, flags *secretsTargetFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "list",
+		Short:         "List secrets and variables and the ones the provider manages",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manager, target, err := secretsContext(flags)
+			if err != nil {
+				logging.GetLogger().Error(err.Error())
+				return err
+			}
+
+			client, err := secrets.NewClient()
+			if err != nil {
+				return err
+			}
+			listed, err := client.List(cmd.Context(), target)
+			if err != nil {
+				logging.GetLogger().Error(err.Error())
+				return err
+			}
+
+			stored := map[string]secrets.Listed{}
+			for _, item := range listed {
+				stored[item.Name] = item
+			}
+
+			fmt.Println(ui.SubHeader(fmt.Sprintf("Managed by the CLI in %s", target)))
+			for _, value := range manager.ManagedValues() {
+				item, ok := stored[value.Name]
+				if !ok {
+					fmt.Printf("  %s %-28s %-9s %s\n", ui.Error("✗"), value.Name, value.Kind, "missing")
+					continue
+				}
+				fmt.Printf("  %s %-28s %-9s updated %s\n", ui.Success("✓"), value.Name, value.Kind, item.UpdatedAt.Local().Format("2006-01-02 15:04"))
+				delete(stored, value.Name)
+			}
+
+			if len(stored) > 0 {
+				fmt.Println(ui.SubHeader("Other"))
+				for _, item := range listed {
+					if _, ok := stored[item.Name]; ok {
+						fmt.Printf("    %-28s %-9s updated %s\n", item.Name, item.Kind, item.UpdatedAt.Local().Format("2006-01-02 15:04"))
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newSecretsSetCommand(ctx context.Context, flags *secretsTargetFlags) *cobra.Command {
+	var variable bool
+
+	cmd := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Set a secret or variable",
+		Long: "Set a secret or variable, reading the value from standard input when it is piped and prompting for it otherwise. " +
+			"Values are never accepted as arguments so they do not end up in the shell history",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+			name := args[0]
+
+			if err := secrets.ValidateName(name); err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			manager, target, err := secretsContext(flags)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			kind := secrets.KindSecret
+			if variable {
+				kind = secrets.KindVariable
+			}
+			for _, value := range manager.ManagedValues() {
+				if value.Name == name && value.Kind != kind && cmd.Flags().Changed("variable") {
+					err := fmt.Errorf("%s is managed as a %s", name, value.Kind)
+					logger.Error(err.Error())
+					return err
+				}
+				if value.Name == name {
+					kind = value.Kind
+				}
+			}
+
+			value, err := readSecretValue(name, kind)
+			if err != nil {
+				if !errors.Is(err, ui.ErrFormCancelled) {
+					logger.Error(err.Error())
+				}
+				return err
+			}
+
+			client, err := secrets.NewClient()
+			if err != nil {
+				return err
+			}
+			report := client.Store(cmd.Context(), target, []secrets.Entry{{Name: name, Value: value, Kind: kind}}, 1)
+			if err := report.Err(); err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			logger.Info(ui.Success(fmt.Sprintf("Set %s %s", kind, name)), "target", target)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&variable, "variable", false, "Set a plain-text variable instead of an encrypted secret")
+
+	return cmd
+}
+
+func newSecretsRotateCommand(ctx context.Context, flags *secretsTargetFlags) *cobra.Command {
+	var opts provider.RotateOptions
+
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the cloud credentials stored in the repository secrets",
+		Long: "Create a new access key with your local cloud credentials, upload it to the repository secrets, " +
+			"verify that it works and delete the old key",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			manager, target, err := secretsContext(flags)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+			opts.Target = target
+
+			if err := manager.RotateCredentials(cmd.Context(), opts); err != nil {
+				logger.Error("Failed to rotate credentials: " + err.Error())
+				return err
+			}
+
+			logger.Info(ui.Success("Rotated the credentials"), "target", target)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Endpoint, "endpoint", "", "Identity service endpoint, e.g. a local emulator (defaults to the provider's public endpoint)")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Local credentials profile performing the rotation")
+	cmd.Flags().StringVar(&opts.User, "user", "", "User whose key is rotated (defaults to the user of the local credentials)")
+	cmd.Flags().StringVar(&opts.OldKeyID, "old-key-id", "", "Key being replaced (defaults to the only key of the user)")
+	cmd.Flags().BoolVar(&opts.KeepOld, "keep-old", false, "Deactivate the old key instead of deleting it")
+	cmd.Flags().DurationVar(&opts.VerifyTimeout, "verify-timeout", 2*time.Minute, "How long to wait for the new key to be accepted")
+
+	return cmd
+}
+
+func newSecretsSyncCommand(ctx context.Context, flags *secretsTargetFlags) *cobra.Command {
+	var variables []string
+	var onlyManaged bool
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "sync FILE",
+		Short: "Upload the values of a .env file",
+		Long: "Upload every NAME=value of a .env style file. Values the provider manages keep their kind, " +
+			"other names are uploaded as secrets unless listed with --variables",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			manager, target, err := secretsContext(flags)
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			pairs, err := secrets.ReadEnvFile(args[0])
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+
+			managed := map[string]secrets.Kind{}
+			for _, value := range manager.ManagedValues() {
+				managed[value.Name] = value.Kind
+			}
+
+			var entries []secrets.Entry
+			var skipped []string
+			for _, pair := range pairs {
+				kind, isManaged := managed[pair.Name]
+				if !isManaged {
+					if onlyManaged {
+						skipped = append(skipped, pair.Name)
+						continue
+					}
+					kind = secrets.KindSecret
+					for _, name := range variables {
+						if name == pair.Name {
+							kind = secrets.KindVariable
+						}
+					}
+				}
+				entries = append(entries, secrets.Entry{Name: pair.Name, Value: pair.Value, Kind: kind})
+			}
+
+			if len(skipped) > 0 {
+				logger.Warning("Skipped values the provider does not manage", "names", skipped)
+			}
+
+			if dryRun {
+				for _, entry := range entries {
+					fmt.Printf("  %-28s %s\n", entry.Name, entry.Kind)
+				}
+				logger.Info("Dry run, nothing was uploaded", "target", target, "values", len(entries))
+				return nil
+			}
+
+			client, err := secrets.NewClient()
+			if err != nil {
+				return err
+			}
+			report := client.Store(cmd.Context(), target, entries, secrets.DefaultConcurrency)
+
+			logger.Info("Synced values", "target", target, "secrets", report.Names(secrets.KindSecret), "variables", report.Names(secrets.KindVariable))
+			if err := report.Err(); err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&variables, "variables", nil, "Names uploaded as plain-text variables instead of secrets")
+	cmd.Flags().BoolVar(&onlyManaged, "only-managed", false, "Only upload the values the provider manages")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be uploaded without uploading it")
+
+	return cmd
+}
+
+// secretsContext returns the secrets manager of the recorded provider and the target flags resolve to
+func secretsContext(flags *secretsTargetFlags) (provider.SecretsManager, secrets.Target, error) {
+	p, m, err := manifestProvider()
+	if err != nil {
+		return nil, secrets.Target{}, err
+	}
+
+	manager, ok := p.(provider.SecretsManager)
+	if !ok {
+		return nil, secrets.Target{}, fmt.Errorf("the %s provider does not manage secrets", p.GetName())
+	}
+
+	scopeName := flags.scope
+	environment := flags.environment
+	if scopeName == "" {
+		scopeName = m.Secrets.Scope
+		if environment == "" {
+			environment = m.Secrets.Environment
+		}
+	}
+
+	scope, err := secrets.ParseScope(scopeName)
+	if err != nil {
+		return nil, secrets.Target{}, err
+	}
+	target, err := secrets.NewTarget(scope, m.Repository, environment)
+	if err != nil {
+		return nil, secrets.Target{}, err
+	}
+	return manager, target, nil
+}
+
+// readSecretValue reads the value of name from piped standard input, or prompts for it
+func readSecretValue(name string, kind secrets.Kind) (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the value from standard input: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var value string
+	input := huh.NewInput().
+		Title(name).
+		Description(fmt.Sprintf("Value of the %s", kind)).
+		Value(&value)
+	if kind == secrets.KindSecret {
+		input = input.EchoMode(huh.EchoModePassword)
+	}
+
+	if err := ui.RunForm(huh.NewForm(huh.NewGroup(input)), nil); err != nil {
+		return "", err
+	}
+	return value, nil
+}
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// statusResult is the structured result of status
+type statusResult struct {
+	Provider       string               `json:"provider" yaml:"provider"`
+	Region         string               `json:"region,omitempty" yaml:"region,omitempty"`
+	Project        string               `json:"project,omitempty" yaml:"project,omitempty"`
+	Bucket         string               `json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	Repository     string               `json:"repository,omitempty" yaml:"repository,omitempty"`
+	RepositoryURL  string               `json:"repositoryUrl,omitempty" yaml:"repositoryUrl,omitempty"`
+	Branch         string               `json:"branch,omitempty" yaml:"branch,omitempty"`
+	DeployWorkflow string               `json:"deployWorkflow,omitempty" yaml:"deployWorkflow,omitempty"`
+	Environments   []string             `json:"environments,omitempty" yaml:"environments,omitempty"`
+	Template       provider.TemplateRef `json:"template" yaml:"template"`
+	CLIVersion     string               `json:"cliVersion,omitempty" yaml:"cliVersion,omitempty"`
+	PreparedAt     *time.Time           `json:"preparedAt,omitempty" yaml:"preparedAt,omitempty"`
+	LatestRun      *provider.RunResult  `json:"latestRun,omitempty" yaml:"latestRun,omitempty"`
+}
+
+func newStatusResult(m *manifest.Manifest) *statusResult {
+	r := &statusResult{
+		Provider:       m.Provider,
+		Region:         m.Region,
+		Project:        m.Project,
+		Bucket:         m.Bucket,
+		Repository:     m.Repository,
+		Branch:         m.Branch,
+		DeployWorkflow: m.DeployWorkflow,
+		Environments:   m.Environments,
+		Template:       provider.TemplateRef{Source: m.Template.Source, Commit: m.Template.Commit, SHA256: m.Template.SHA256},
+		CLIVersion:     m.CLIVersion,
+	}
+	if m.Repository != "" {
+		r.RepositoryURL = "https://github.com/" + m.Repository
+	}
+	if !m.PreparedAt.IsZero() {
+		r.PreparedAt = &m.PreparedAt
+	}
+	return r
+}
+
+func NewStatusCommand(ctx context.Context) *cobra.Command {
+	var output *resultOutput
+
+	cmd := &cobra.Command{
+		Use:           "status",
+		Short:         "Show what was prepared and the latest workflow run",
+		Long:          "Show the choices recorded in " + manifest.FileName + " and the latest workflow run of the repository",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			m, err := manifest.Read(".")
+			if err != nil {
+				logger.Error(err.Error())
+				output.Print(cmd, nil, err)
+				return err
+			}
+
+			result := newStatusResult(m)
+			defer func() {
+				output.Print(cmd, result, nil)
+			}()
+
+			w := output.Human()
+			printManifest(w, m)
+
+			if m.Repository == "" {
+				return nil
+			}
+			run, err := github.GetLatestWorkflowRun(cmd.Context(), m.Repository, m.Branch)
+			if err != nil {
+				logger.Warning("Could not fetch the latest workflow run", "error", err)
+				return nil
+			}
+			if run != nil {
+				result.LatestRun = provider.NewRunResult(run)
+
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, ui.SubHeader("Latest workflow run"))
+				printField(w, "Workflow", run.Name)
+				printField(w, "Status", runStatus(run))
+				printField(w, "URL", run.URL)
+			}
+			return nil
+		},
+	}
+
+	output = addOutputFlag(cmd)
+
+	return cmd
+}
+
+func printManifest(w io.Writer, m *manifest.Manifest) {
+	fmt.Fprintln(w, ui.SubHeader("Project"))
+	printField(w, "Provider", ui.LegibleProviderName(m.Provider))
+	printField(w, "Region", m.Region)
+	printField(w, "Project", m.Project)
+	printField(w, "State bucket", m.Bucket)
+	printField(w, "Repository", m.Repository)
+	printField(w, "Branch", m.Branch)
+	printField(w, "Deploy workflow", m.DeployWorkflow)
+	printField(w, "Environments", strings.Join(m.Environments, ", "))
+	printField(w, "Secrets scope", strings.TrimSpace(m.Secrets.Scope+" "+m.Secrets.Environment))
+	printField(w, "Template", m.Template.Source)
+	printField(w, "Template commit", m.Template.Commit)
+	printField(w, "Prepared with CLI", m.CLIVersion)
+	if !m.PreparedAt.IsZero() {
+		printField(w, "Prepared at", m.PreparedAt.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+func printField(w io.Writer, label, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(w, "  %-18s %s\n", label+":", value)
+}
+
+func runStatus(run *github.WorkflowRun) string {
+	if run.Status != "completed" {
+		return ui.Highlight(run.Status)
+	}
+	if run.Conclusion == "success" {
+		return ui.Success(run.Conclusion)
+	}
+	return ui.Error(run.Conclusion)
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/resources"
+	"github.com/blazity/enterprise-cli/pkg/templates"
+	"github.com/blazity/enterprise-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// SkipEarlyChecksAnnotation marks commands that do not need a Git repository or GitHub authentication
+const SkipEarlyChecksAnnotation = "enterprise/skip-early-checks"
+
+func NewTemplateCommand(ctx context.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Work with infrastructure templates",
+		Long:  "Inspect and validate the infrastructure templates used by the prepare command",
+	}
+
+	cmd.AddCommand(newTemplateLintCommand(ctx))
+	cmd.AddCommand(newTemplateFetchCommand(ctx))
+
+	return cmd
+}
+
+func newTemplateLintCommand(ctx context.Context) *cobra.Command {
+	var providerName string
+
+	cmd := &cobra.Command{
+		Use:           "lint [dir]",
+		Short:         "Validate the _map.yml of a template",
+		Long:          "Validate the _map.yml resource mappings of a template directory against the schema",
+		Args:          cobra.MaximumNArgs(1),
+		Annotations:   map[string]string{SkipEarlyChecksAnnotation: "true"},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+
+			p, exists := provider.Get(providerName)
+			if !exists {
+				return fmt.Errorf("provider not supported: %s, available providers: %s", providerName, strings.Join(provider.ListAvailableProviders(), ", "))
+			}
+			var variables []string
+			if tv, ok := p.(provider.TemplateVariables); ok {
+				variables = tv.ResourceVariableNames()
+			}
+
+			issues, err := resources.Lint(dir, variables)
+			for _, issue := range issues {
+				if issue.Severity == resources.SeverityError {
+					fmt.Println(ui.Error("✗ ") + issue.String())
+				} else {
+					fmt.Println(ui.Highlight("! ") + issue.String())
+				}
+			}
+
+			if resources.HasErrors(issues) {
+				return fmt.Errorf("template validation failed with %d issue(s)", len(issues))
+			}
+			if err != nil {
+				return err
+			}
+
+			logger.Info(ui.Success("Template is valid"), "warnings", len(issues))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&providerName, "provider", "aws", "Provider whose variables the conditions of the template may use")
+
+	return cmd
+}
+
+func newTemplateFetchCommand(ctx context.Context) *cobra.Command {
+	var pinnedCommit string
+	var pinnedSHA256 string
+
+	cmd := &cobra.Command{
+		Use:           "fetch [template]",
+		Short:         "Download a template into the local cache",
+		Long:          "Download a template into the local cache so that prepare can work offline, and print its commit and checksum for pinning",
+		Args:          cobra.MaximumNArgs(1),
+		Annotations:   map[string]string{SkipEarlyChecksAnnotation: "true"},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			value := ""
+			if len(args) == 1 {
+				value = args[0]
+			}
+			src, err := templates.ParseSource(value)
+			if err != nil {
+				return err
+			}
+			if src.Kind != templates.KindGitHub {
+				return fmt.Errorf("only GitHub templates are cached, %s can be used directly", src)
+			}
+
+			cache, err := templates.NewCache()
+			if err != nil {
+				return err
+			}
+
+			tempDir, err := os.MkdirTemp("", "enterprise-template-*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tempDir)
+
+			resolved, err := templates.Fetch(cmd.Context(), src, tempDir, templates.FetchOptions{
+				Cache:        cache,
+				PinnedCommit: pinnedCommit,
+				PinnedSHA256: pinnedSHA256,
+			})
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Template cached", "source", src.String(), "path", cache.Dir())
+			fmt.Printf("commit: %s\nsha256: %s\n", resolved.Commit, resolved.SHA256)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pinnedCommit, "commit", "", "Fail unless the template resolves to this commit")
+	cmd.Flags().StringVar(&pinnedSHA256, "sha256", "", "Fail unless the template content matches this SHA-256 checksum")
+
+	return cmd
+}
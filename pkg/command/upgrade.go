@@ -0,0 +1,100 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/spf13/cobra"
+)
+
+func NewUpgradeCommand(ctx context.Context) *cobra.Command {
+	var opts provider.UpgradeOptions
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the template files of a prepared repository",
+		Long: "Fetch a newer template version, render it the way prepare did and three-way merge the upstream changes " +
+			"into the repository. Files changed on both sides are left with conflict markers for review.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.GetLogger()
+
+			p, m, err := manifestProvider()
+			if err != nil {
+				logger.Error(err.Error())
+				return err
+			}
+			upgrader, ok := p.(provider.Upgrader)
+			if !ok {
+				logger.Error("Provider does not support upgrades: " + m.Provider)
+				return fmt.Errorf("provider does not support upgrades: %s", m.Provider)
+			}
+
+			summary, err := upgrader.UpgradeWithOptions(cmd.Context(), opts)
+			if summary != nil {
+				printUpgradeSummary(summary)
+			}
+			if err != nil {
+				logger.Error("Failed to upgrade: " + err.Error())
+				return err
+			}
+
+			if summary.Stash != "" {
+				logger.Warning("Your uncommitted changes are stashed, restore them with git stash pop once the upgrade is reviewed", "stash", summary.Stash)
+			}
+
+			if len(summary.Conflicts) > 0 {
+				if summary.Worktree != "" {
+					logger.Warning("Resolve the conflict markers in the temporary worktree, then commit the changes to its branch", "worktree", summary.Worktree, "branch", summary.Branch)
+				} else {
+					logger.Warning("Resolve the conflict markers, then review and commit the changes")
+				}
+				return fmt.Errorf("%d file(s) have conflicts", len(summary.Conflicts))
+			}
+			if summary.Branch != "" {
+				logger.Info("Upgrade completed and committed to a new branch, review and merge it", "branch", summary.Branch)
+				return nil
+			}
+			logger.Info("Upgrade completed, review and commit the changes")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Template, "template", "", "Template source to upgrade to: owner/repo[@ref], a local directory or a .tar.gz archive (defaults to the recorded source)")
+	cmd.Flags().StringVar(&opts.TemplateCommit, "template-commit", "", "Fail unless the new template resolves to this commit")
+	cmd.Flags().StringVar(&opts.TemplateSHA256, "template-sha256", "", "Fail unless the new template content matches this SHA-256 checksum")
+	cmd.Flags().StringVar(&opts.DirtyTree, "dirty-tree", string(provider.DirtyTreePrompt), "What to do with uncommitted changes: prompt, abort, stash, or worktree to upgrade in a temporary git worktree")
+
+	return cmd
+}
+
+func printUpgradeSummary(summary *provider.UpgradeSummary) {
+	logger := logging.GetLogger()
+
+	if summary.FromCommit != "" || summary.ToCommit != "" {
+		logger.Info("Template versions", "from", summary.FromCommit, "to", summary.ToCommit)
+	}
+
+	groups := []struct {
+		label string
+		paths []string
+	}{
+		{"Updated", summary.Updated},
+		{"Added", summary.Added},
+		{"Removed", summary.Removed},
+		{"Merged", summary.Merged},
+		{"Conflicts", summary.Conflicts},
+		{"Skipped", summary.Skipped},
+	}
+	for _, group := range groups {
+		if len(group.paths) == 0 {
+			continue
+		}
+		fmt.Printf("%s (%d):\n  %s\n", group.label, len(group.paths), strings.Join(group.paths, "\n  "))
+	}
+}
@@ -0,0 +1,118 @@
+package diagnose
+
+import (
+	"regexp"
+)
+
+// Problem is a known cause of a failed deployment
+type Problem struct {
+	ID          string
+	Title       string
+	Explanation string
+	Fix         string
+
+	patterns []*regexp.Regexp
+}
+
+// match reports whether text shows the problem, returning the line it was recognised on
+func (p Problem) match(text string, block ErrorBlock) (string, bool) {
+	for _, pattern := range p.patterns {
+		if !pattern.MatchString(text) {
+			continue
+		}
+		for _, line := range block.Lines {
+			if pattern.MatchString(line) {
+				return line, true
+			}
+		}
+		return block.Summary(), true
+	}
+	return "", false
+}
+
+func problem(id string, title string, explanation string, fix string, patterns ...string) Problem {
+	p := Problem{ID: id, Title: title, Explanation: explanation, Fix: fix}
+	for _, pattern := range patterns {
+		p.patterns = append(p.patterns, regexp.MustCompile(pattern))
+	}
+	return p
+}
+
+// Catalogue lists the known problems, most specific first
+var Catalogue = []Problem{
+	problem("aws-invalid-credentials",
+		"AWS rejected the access key",
+		"The AWS_ACCESS_KEY_ID secret does not belong to an active access key, usually because the key was deleted, deactivated or mistyped.",
+		"Create a new access key for the deploying IAM user and store it with `enterprise secrets rotate`, or set both secrets with `enterprise secrets set`.",
+		`InvalidClientTokenId`, `The security token included in the request is invalid`, `InvalidAccessKeyId`),
+	problem("aws-signature-mismatch",
+		"AWS rejected the secret access key",
+		"The AWS_SECRET_ACCESS_KEY secret does not match the access key, often because of a copy and paste error or trailing whitespace.",
+		"Store the secret key again with `enterprise secrets set AWS_SECRET_ACCESS_KEY`.",
+		`SignatureDoesNotMatch`),
+	problem("aws-expired-token",
+		"The AWS credentials have expired",
+		"Temporary credentials were stored as secrets, and they expire after a few hours.",
+		"Use the long-lived access key of an IAM user for the repository secrets instead of session credentials.",
+		`ExpiredToken`, `RequestExpired`),
+	problem("aws-missing-credentials",
+		"No AWS credentials reached the workflow",
+		"The workflow found no AWS credentials, because the secrets are missing or are stored in an environment the job does not declare.",
+		"Check them with `enterprise secrets list`, and declare the environment in the job when using `--secrets-scope environment`.",
+		`No valid credential sources found`, `NoCredentialProviders`, `failed to refresh cached credentials`, `Unable to locate credentials`),
+	problem("s3-bucket-name-taken",
+		"The S3 bucket name is already taken",
+		"S3 bucket names are global across all AWS accounts, and another account already owns this name.",
+		"Choose a more unique bucket or project name, update it with `enterprise secrets set AWS_TERRAFORM_BUCKET_NAME --variable` and in the Terraform variables, then deploy again.",
+		`BucketAlreadyExists\b`),
+	problem("s3-bucket-already-owned",
+		"The S3 bucket already exists in your account",
+		"Terraform tried to create a bucket your account already owns, typically left behind by an earlier deployment whose state was lost.",
+		"Import the bucket into the Terraform state with `terraform import`, or delete it if it is unused, then deploy again.",
+		`BucketAlreadyOwnedByYou`),
+	problem("terraform-state-bucket-missing",
+		"The Terraform state bucket does not exist",
+		"The backend bucket configured in AWS_TERRAFORM_BUCKET_NAME has not been created, or lives in another region.",
+		"Create the bucket in the region of AWS_REGION, or point AWS_TERRAFORM_BUCKET_NAME at the existing one.",
+		`NoSuchBucket`, `S3 bucket does not exist`, `Failed to get existing workspaces`),
+	problem("terraform-state-locked",
+		"The Terraform state is locked",
+		"Another run holds the state lock, or a cancelled run left it behind.",
+		"Wait for the other run to finish. If none is running, release the lock with `terraform force-unlock <lock id>` using the ID printed in the error.",
+		`Error acquiring the state lock`, `ConditionalCheckFailedException`),
+	problem("aws-access-denied",
+		"The IAM user is not allowed to perform an action",
+		"The deploying IAM user lacks a permission the workflow needs.",
+		"Grant the action named in the error to the IAM user, for example by attaching the policy suggested in the template README, then re-run the failed jobs.",
+		`AccessDenied`, `UnauthorizedOperation`, `is not authorized to perform`),
+	problem("aws-resource-exists",
+		"A resource already exists outside of Terraform",
+		"Terraform tried to create a resource with a name that is already in use in the account, usually from an earlier deployment whose state was lost.",
+		"Import the existing resource into the state with `terraform import`, or remove it, then deploy again.",
+		`EntityAlreadyExists`, `ResourceAlreadyExistsException`, `RepositoryAlreadyExistsException`, `AlreadyExistsException`),
+	problem("aws-limit-exceeded",
+		"An AWS service quota was reached",
+		"The account hit a service limit, such as the number of VPCs or Elastic IPs in the region.",
+		"Remove unused resources or request a quota increase in the Service Quotas console for the region in AWS_REGION.",
+		`LimitExceeded`, `AddressLimitExceeded`, `VpcLimitExceeded`, `TooManyBuckets`),
+	problem("aws-opt-in-region",
+		"The region is not enabled for the account",
+		"Some AWS regions must be enabled before use, and calls to a disabled region fail.",
+		"Enable the region in the AWS account settings, or change AWS_REGION with `enterprise secrets set AWS_REGION --variable`.",
+		`OptInRequired`, `not subscribed to this service`),
+	problem("terraform-init-required",
+		"Terraform needs to be initialised again",
+		"The backend or provider configuration changed since the working directory was initialised.",
+		"Make sure the workflow runs `terraform init` before plan and apply, with `-reconfigure` after a backend change.",
+		`Backend initialization required`, `Inconsistent dependency lock file`),
+	problem("terraform-version",
+		"The Terraform version is not supported",
+		"The configuration requires a different Terraform version than the one installed by the workflow.",
+		"Align the Terraform version installed by the workflow with the `required_version` of the configuration.",
+		`Unsupported Terraform Core version`),
+	problem("docker-build-failed",
+		"The container image failed to build",
+		"The Next.js application failed to install or build inside the Dockerfile.",
+		"Run the build locally with `docker build` from the repository root, and regenerate the Dockerfile with `enterprise generate docker` if the package manager or Node.js version changed.",
+		`failed to solve`, `npm ERR!`, `ERR_PNPM_`, `error Command failed with exit code`),
+}
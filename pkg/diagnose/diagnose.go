@@ -0,0 +1,277 @@
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/ui"
+)
+
+// maxEvidence bounds the length of an error line quoted in a diagnosis
+const maxEvidence = 240
+
+// LogLine is a line of a workflow run log as printed by gh run view --log
+type LogLine struct {
+	Job  string
+	Step string
+	Text string
+}
+
+// ErrorBlock is a Terraform diagnostic, an AWS CLI error or a workflow error annotation
+type ErrorBlock struct {
+	Lines []string
+}
+
+// Summary returns the first line of the block
+func (b ErrorBlock) Summary() string {
+	if len(b.Lines) == 0 {
+		return ""
+	}
+	return b.Lines[0]
+}
+
+func (b ErrorBlock) String() string {
+	return strings.Join(b.Lines, "\n")
+}
+
+// Failure groups the errors of a failed step
+type Failure struct {
+	Job      string
+	Step     string
+	Errors   []ErrorBlock
+	Findings []Finding
+}
+
+// Finding is a known problem recognised in the errors of a failure
+type Finding struct {
+	Problem  Problem
+	Evidence string
+}
+
+// Diagnosis explains why a workflow run failed
+type Diagnosis struct {
+	Run      *github.WorkflowRun
+	Failures []Failure
+}
+
+// Recognised reports whether any failure matched a known problem
+func (d *Diagnosis) Recognised() bool {
+	for _, failure := range d.Failures {
+		if len(failure.Findings) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnose downloads the logs of the failed jobs of a workflow run and analyses them
+func Diagnose(ctx context.Context, repo string, run *github.WorkflowRun) (*Diagnosis, error) {
+	log, err := github.GetFailedWorkflowRunLogs(ctx, run.ID, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	d := Analyze(log)
+	d.Run = run
+	return d, nil
+}
+
+// Analyze extracts the failed steps and their errors from a workflow run log and matches them
+// against the catalogue of known problems
+func Analyze(log string) *Diagnosis {
+	d := &Diagnosis{}
+	index := map[string]int{}
+
+	lines := ParseLog(log)
+	for start := 0; start < len(lines); {
+		end := start
+		for end < len(lines) && lines[end].Job == lines[start].Job && lines[end].Step == lines[start].Step {
+			end++
+		}
+
+		key := lines[start].Job + "\x00" + lines[start].Step
+		i, seen := index[key]
+		if !seen {
+			i = len(d.Failures)
+			index[key] = i
+			d.Failures = append(d.Failures, Failure{Job: lines[start].Job, Step: lines[start].Step})
+		}
+		d.Failures[i].Errors = append(d.Failures[i].Errors, extractErrors(lines[start:end])...)
+
+		start = end
+	}
+
+	for i := range d.Failures {
+		d.Failures[i].Findings = match(d.Failures[i].Errors)
+	}
+	return d
+}
+
+var timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z ?`)
+
+// ansiPattern matches terminal color sequences Terraform and npm print in CI
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// ParseLog splits a "job<TAB>step<TAB>timestamp text" log into lines, stripping timestamps and colors.
+// Lines that do not follow the format are attributed to an unknown job and step
+func ParseLog(log string) []LogLine {
+	var lines []LogLine
+	for _, raw := range strings.Split(strings.ReplaceAll(log, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+
+		line := LogLine{Text: raw}
+		if parts := strings.SplitN(raw, "\t", 3); len(parts) == 3 {
+			line = LogLine{Job: parts[0], Step: parts[1], Text: parts[2]}
+		}
+		line.Text = ansiPattern.ReplaceAllString(timestampPattern.ReplaceAllString(strings.TrimPrefix(line.Text, "\ufeff"), ""), "")
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+var (
+	awsCLIErrorPattern = regexp.MustCompile(`An error occurred \(([A-Za-z0-9.]+)\)`)
+	annotationPattern  = regexp.MustCompile(`^##\[error\](.*)$`)
+)
+
+// extractErrors collects the Terraform diagnostics, AWS CLI errors and error annotations of a step.
+// The generic exit code annotation is only kept when nothing more specific was found
+func extractErrors(lines []LogLine) []ErrorBlock {
+	var blocks []ErrorBlock
+	var annotations []ErrorBlock
+
+	for i := 0; i < len(lines); i++ {
+		text := strings.TrimSpace(lines[i].Text)
+
+		switch {
+		case strings.HasPrefix(text, "╷"):
+			// Terraform diagnostic box, closed by ╵
+			var block ErrorBlock
+			for i++; i < len(lines); i++ {
+				inner := strings.TrimSpace(lines[i].Text)
+				if strings.HasPrefix(inner, "╵") {
+					break
+				}
+				inner = strings.TrimSpace(strings.TrimPrefix(inner, "│"))
+				if inner != "" {
+					block.Lines = append(block.Lines, inner)
+				}
+			}
+			if len(block.Lines) > 0 && strings.HasPrefix(block.Lines[0], "Error") {
+				blocks = append(blocks, block)
+			}
+
+		case strings.HasPrefix(text, "Error: "):
+			// Terraform diagnostic without the box, followed by indented details
+			block := ErrorBlock{Lines: []string{text}}
+			for i+1 < len(lines) && strings.HasPrefix(lines[i+1].Text, " ") && strings.TrimSpace(lines[i+1].Text) != "" {
+				i++
+				block.Lines = append(block.Lines, strings.TrimSpace(lines[i].Text))
+			}
+			blocks = append(blocks, block)
+
+		case awsCLIErrorPattern.MatchString(text):
+			blocks = append(blocks, ErrorBlock{Lines: []string{text}})
+
+		case annotationPattern.MatchString(text):
+			message := strings.TrimSpace(annotationPattern.FindStringSubmatch(text)[1])
+			if message != "" {
+				annotations = append(annotations, ErrorBlock{Lines: []string{message}})
+			}
+		}
+	}
+
+	if len(blocks) > 0 {
+		for _, annotation := range annotations {
+			if !strings.HasPrefix(annotation.Summary(), "Process completed with exit code") {
+				blocks = append(blocks, annotation)
+			}
+		}
+		return blocks
+	}
+	return annotations
+}
+
+// match returns the known problems found in blocks, each at most once
+func match(blocks []ErrorBlock) []Finding {
+	var findings []Finding
+	seen := map[string]bool{}
+
+	for _, block := range blocks {
+		text := block.String()
+		for _, problem := range Catalogue {
+			if seen[problem.ID] {
+				continue
+			}
+			if evidence, ok := problem.match(text, block); ok {
+				seen[problem.ID] = true
+				findings = append(findings, Finding{Problem: problem, Evidence: evidence})
+			}
+		}
+	}
+	return findings
+}
+
+// Render formats the diagnosis for the terminal
+func (d *Diagnosis) Render() string {
+	var b strings.Builder
+
+	if d.Run != nil {
+		b.WriteString(ui.SubHeader(fmt.Sprintf("Diagnosis of run %s (%s)", d.Run.ID, d.Run.Name)) + "\n")
+		if d.Run.URL != "" {
+			b.WriteString("  " + d.Run.URL + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(d.Failures) == 0 {
+		b.WriteString("  No failed steps were found in the logs. The run may have been cancelled or failed before any step started\n")
+		return b.String()
+	}
+
+	for _, failure := range d.Failures {
+		b.WriteString(fmt.Sprintf("%s %s failed at step %s\n", ui.Error("✗"), ui.Highlight(orUnknown(failure.Job)), ui.Highlight(orUnknown(failure.Step))))
+
+		for _, finding := range failure.Findings {
+			b.WriteString(fmt.Sprintf("\n  %s\n", ui.SubHeader(finding.Problem.Title)))
+			b.WriteString(fmt.Sprintf("  %s\n", ui.FormDescriptionStyle.Render(truncate(finding.Evidence, maxEvidence))))
+			b.WriteString(fmt.Sprintf("\n  %s\n", finding.Problem.Explanation))
+			b.WriteString(fmt.Sprintf("  %s %s\n", ui.Success("Fix:"), finding.Problem.Fix))
+		}
+
+		if len(failure.Findings) == 0 {
+			if len(failure.Errors) == 0 {
+				b.WriteString("  No error messages were recognised, view the full logs with `enterprise runs watch`\n")
+			}
+			for _, block := range failure.Errors {
+				b.WriteString("  " + truncate(block.Summary(), maxEvidence) + "\n")
+				for _, line := range block.Lines[1:min(len(block.Lines), 4)] {
+					b.WriteString("    " + ui.FormDescriptionStyle.Render(truncate(line, maxEvidence)) + "\n")
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "(unknown)"
+	}
+	return s
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
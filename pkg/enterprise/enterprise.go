@@ -9,16 +9,21 @@ import (
 	"github.com/blazity/enterprise-cli/pkg/github"
 	"github.com/blazity/enterprise-cli/pkg/logging"
 	_ "github.com/blazity/enterprise-cli/pkg/provider/aws"
+	"github.com/blazity/enterprise-cli/pkg/runner"
+	"github.com/blazity/enterprise-cli/pkg/version"
 	"github.com/spf13/cobra"
 )
 
 func NewEnterpriseCommand(ctx context.Context, cancel context.CancelFunc) *cobra.Command {
 	var verbose bool
+	var record, replay, simulate string
+	var logFormat, logFile string
 
 	rootCmd := &cobra.Command{
-		Use:   "enterprise",
-		Short: "Enterprise CLI for infrastructure management",
-		Long:  "Enterprise CLI for preparing and deploying infrastructure across various providers in the next-enterprise repository",
+		Use:     "enterprise",
+		Short:   "Enterprise CLI for infrastructure management",
+		Long:    "Enterprise CLI for preparing and deploying infrastructure across various providers in the next-enterprise repository",
+		Version: version.String(),
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			logger := logging.GetLogger()
 			if verbose {
@@ -34,13 +39,46 @@ func NewEnterpriseCommand(ctx context.Context, cancel context.CancelFunc) *cobra
 				}
 			}
 
-			performEarlyChecks()
+			if err := setupLogOutput(cmd, logger, logFormat, logFile); err != nil {
+				logger.Error(err.Error())
+				os.Exit(1)
+			}
+
+			if err := setupRunner(record, replay, simulate); err != nil {
+				logger.Error(err.Error())
+				os.Exit(1)
+			}
+
+			if cmd.Annotations[command.SkipEarlyChecksAnnotation] == "true" {
+				return
+			}
+
+			performEarlyChecks(cmd.Context())
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if err := logging.GetLogger().Close(); err != nil {
+				logging.GetLogger().Warning(err.Error())
+			}
 		},
 	}
 
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", string(logging.FormatText), "Console log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also write every log line, including debug output and the run ID, to a file")
+	rootCmd.PersistentFlags().StringVar(&record, "record", "", "Record the git and gh commands and GitHub API requests to a fixture file")
+	rootCmd.PersistentFlags().StringVar(&replay, "replay", "", "Answer every command and GitHub API request from a fixture file instead of running it")
+	rootCmd.PersistentFlags().StringVar(&simulate, "simulate", "", "Run git locally but answer gh and GitHub API requests from a fixture file, for demos without a GitHub account")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay", "simulate")
 
 	rootCmd.AddCommand(command.NewPrepareCommand(ctx))
+	rootCmd.AddCommand(command.NewDeployCommand(ctx))
+	rootCmd.AddCommand(command.NewStatusCommand(ctx))
+	rootCmd.AddCommand(command.NewGenerateCommand(ctx))
+	rootCmd.AddCommand(command.NewTemplateCommand(ctx))
+	rootCmd.AddCommand(command.NewUpgradeCommand(ctx))
+	rootCmd.AddCommand(command.NewDestroyCommand(ctx))
+	rootCmd.AddCommand(command.NewSecretsCommand(ctx))
+	rootCmd.AddCommand(command.NewRunsCommand(ctx))
 
 	rootCmd.SetHelpTemplate(`{{.Short}}
 
@@ -58,7 +96,51 @@ func InitializeLogger(verbose bool) logging.Logger {
 	return logging.GetLogger()
 }
 
-func performEarlyChecks() {
+// setupLogOutput applies the --log-format and --log-file flags
+func setupLogOutput(cmd *cobra.Command, logger logging.Logger, format string, file string) error {
+	f, err := logging.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	logger.SetFormat(f)
+
+	if file != "" {
+		if err := logger.SetLogFile(file); err != nil {
+			return err
+		}
+	}
+	// Only the command path is logged, since flag values may hold secrets
+	logger.Event("run_start", "command", cmd.CommandPath(), "version", version.String())
+	return nil
+}
+
+// setupRunner installs the command runner selected by the --record, --replay and --simulate flags
+func setupRunner(record string, replay string, simulate string) error {
+	logger := logging.GetLogger()
+
+	switch {
+	case record != "":
+		runner.Set(runner.NewRecorder(runner.Exec{}, record))
+		logger.Warning("Recording commands and GitHub API responses, review the fixture for private data before sharing it", "fixture", record)
+	case replay != "":
+		fixture, err := runner.LoadFixture(replay)
+		if err != nil {
+			return err
+		}
+		runner.Set(runner.NewReplayer(fixture))
+		logger.Debug("Replaying commands", "fixture", replay, "interactions", len(fixture.Interactions))
+	case simulate != "":
+		fixture, err := runner.LoadFixture(simulate)
+		if err != nil {
+			return err
+		}
+		runner.Set(runner.NewReplayer(fixture, "git"))
+		logger.Info("Simulating GitHub, nothing is created or changed on GitHub", "fixture", simulate)
+	}
+	return nil
+}
+
+func performEarlyChecks(ctx context.Context) {
 	_, err := exec.LookPath("git")
 	if err != nil {
 		logger := logging.GetLogger()
@@ -72,7 +154,7 @@ func performEarlyChecks() {
 		os.Exit(1)
 	}
 
-	authStatus := github.CheckAuthStatus()
+	authStatus := github.CheckAuthStatus(ctx)
 	if !authStatus.CliInstalled {
 		logger := logging.GetLogger()
 		logger.Error("GitHub CLI is not installed. This tool requires GitHub CLI.")
@@ -0,0 +1,172 @@
+package enterprise
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/blazity/enterprise-cli/pkg/runner"
+	"github.com/blazity/enterprise-cli/pkg/utils/filesystem"
+)
+
+// TestPrepareAwsReplay runs `prepare aws` non-interactively against a recorded fixture, in which git, gh, npx
+// and the GitHub API are all answered from testdata/prepare-aws/fixture.yml
+func TestPrepareAwsReplay(t *testing.T) {
+	testdata, err := filepath.Abs(filepath.Join("testdata", "prepare-aws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := filepath.Join(testdata, "fixture.yml")
+
+	repo := t.TempDir()
+	template := t.TempDir()
+	if err := filesystem.CopyDir(filepath.Join(testdata, "repo"), repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := filesystem.CopyDir(filepath.Join(testdata, "template"), template); err != nil {
+		t.Fatal(err)
+	}
+	// git itself is replayed, only the early checks look for the repository on disk
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, repo)
+	t.Cleanup(func() { runner.Set(runner.Exec{}) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stdout := captureStdout(t, func() {
+		cmd := NewEnterpriseCommand(ctx, cancel)
+		cmd.SetArgs([]string{
+			"--replay", fixture,
+			"prepare", "aws",
+			"--answers", filepath.Join(testdata, "answers.yml"),
+			"--template", template,
+			"--dirty-tree", "abort",
+			"--output", "json",
+		})
+		if err := cmd.ExecuteContext(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var output struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Result struct {
+			Repository string `json:"repository"`
+			Branch     string `json:"branch"`
+			Commits    []struct {
+				Subject string `json:"subject"`
+			} `json:"commits"`
+			Secrets []string `json:"secrets"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(stdout, &output); err != nil {
+		t.Fatalf("failed to parse the result: %v\n%s", err, stdout)
+	}
+	if output.Status != "succeeded" {
+		t.Fatalf("prepare %s: %s", output.Status, output.Error)
+	}
+	if output.Result.Repository != "acme/shop" || output.Result.Branch != "main" {
+		t.Errorf("prepared %s on %s, want acme/shop on main", output.Result.Repository, output.Result.Branch)
+	}
+
+	var subjects []string
+	for _, commit := range output.Result.Commits {
+		subjects = append(subjects, commit.Subject)
+	}
+	wantSubjects := []string{
+		"chore(ci): configure github actions for aws",
+		"chore(aws): add terraform files",
+		"chore(aws): modify hcl to reflect user input",
+		"chore(aws): add next.config.ts codemod",
+		"chore(aws): add standalone dependencies to package.json",
+		"chore(aws): add all remaining resources",
+		"chore(aws): move old repository to frontend/ sub dir",
+	}
+	if !slices.Equal(subjects, wantSubjects) {
+		t.Errorf("commits = %q, want %q", subjects, wantSubjects)
+	}
+
+	replayer, ok := runner.Get().(*runner.Replayer)
+	if !ok {
+		t.Fatalf("runner is %T, want a replayer", runner.Get())
+	}
+	for _, interaction := range replayer.Remaining() {
+		t.Errorf("recorded interaction was not replayed: %s %s", interaction.Command, strings.Join(interaction.Args, " "))
+	}
+
+	recorded, err := runner.LoadFixture(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	for _, interaction := range recorded.Interactions {
+		calls = append(calls, strings.TrimSpace(interaction.Command+" "+strings.Join(interaction.Args, " ")))
+	}
+	wantCalls := []string{
+		"gh repo create acme/shop --private",
+		"http GET /repos/acme/shop/actions/secrets/public-key",
+		"http PUT /repos/acme/shop/actions/secrets/AWS_ACCESS_KEY_ID",
+		"http PUT /repos/acme/shop/actions/secrets/AWS_SECRET_ACCESS_KEY",
+		"gh api -X PUT repos/acme/shop/actions/permissions -H Accept: application/vnd.github+json -H X-GitHub-Api-Version: 2022-11-28 -F enabled=true -F allowed_actions=all",
+		"git -C . push -u origin enterprise-aws-setup-",
+		"http PUT /repos/acme/shop/branches/main/protection",
+		"http PUT /repos/acme/shop/environments/production",
+	}
+	for _, call := range wantCalls {
+		if !slices.ContainsFunc(calls, func(recorded string) bool { return strings.HasPrefix(recorded, call) }) {
+			t.Errorf("fixture does not record %q", call)
+		}
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+}
+
+// captureStdout returns what fn writes to os.Stdout, where structured results are printed
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+
+	func() {
+		// Restored even when fn stops the test
+		defer func() {
+			os.Stdout = stdout
+			w.Close()
+		}()
+		fn()
+	}()
+	<-done
+	return buf.Bytes()
+}
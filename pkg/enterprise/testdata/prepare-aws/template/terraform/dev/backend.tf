@@ -0,0 +1,7 @@
+terraform {
+  backend "s3" {
+    bucket = "next-enterprise-terraform"
+    key    = "dev/terraform.tfstate"
+    region = "us-east-1"
+  }
+}
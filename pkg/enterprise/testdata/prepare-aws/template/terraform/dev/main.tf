@@ -0,0 +1,11 @@
+locals {
+  project_name = "next-enterprise"
+}
+
+provider "aws" {
+  region = "us-east-1"
+}
+
+module "vpc" {
+  source = "../module"
+}
@@ -0,0 +1,3 @@
+resource "aws_subnet" "a" {
+  availability_zone = "us-east-1a"
+}
@@ -0,0 +1,277 @@
+package generate
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/blazity/enterprise-cli/pkg/codemod"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+// DefaultNodeVersion is used when the project does not pin a Node.js version
+const DefaultNodeVersion = "22"
+
+// DockerConfig holds configuration for the Dockerfile generator.
+// AppDir is the directory containing the Next.js application, where the files are written,
+// and BuildContext is the same directory as seen from the repository root once preparation is done.
+// WorkspaceDir is set when the application is a package of a workspace whose root holds the lockfile,
+// it is the application directory as seen from the workspace root, which then becomes the build context.
+// LockfileStale installs without freezing the lockfile, for a lockfile that no longer matches package.json.
+type DockerConfig struct {
+	AppDir         string
+	BuildContext   string
+	WorkspaceDir   string
+	PackageManager codemod.PackageManager
+	NodeVersion    string
+	LockfileStale  bool
+	Force          bool
+}
+
+// NewDefaultDockerConfig returns a default DockerConfig
+func NewDefaultDockerConfig() *DockerConfig {
+	return &DockerConfig{
+		AppDir:       ".",
+		BuildContext: ".",
+	}
+}
+
+type dockerTemplateData struct {
+	PackageManager  codemod.PackageManager
+	NodeVersion     string
+	BuildContext    string
+	WorkspaceDir    string
+	ImageName       string
+	DependencyFiles string
+	HasYarnDir      bool
+	InstallCommand  string
+	BuildCommand    string
+	HasPublicDir    bool
+}
+
+// GenerateDocker writes a multi-stage Dockerfile and a .dockerignore into cfg.AppDir.
+// Existing files are left untouched unless cfg.Force is set. It returns the paths it wrote.
+func GenerateDocker(cfg *DockerConfig) ([]string, error) {
+	logger := logging.GetLogger()
+
+	if _, err := os.Stat(filepath.Join(cfg.AppDir, "package.json")); err != nil {
+		return nil, fmt.Errorf("package.json not found in %s: %w", cfg.AppDir, err)
+	}
+
+	// A workspace keeps its lockfile, .yarnrc.yml and packageManager field at the root
+	depsDir := cfg.AppDir
+	if cfg.WorkspaceDir != "" {
+		depsDir = "."
+	}
+
+	if cfg.PackageManager == "" {
+		cfg.PackageManager = codemod.DetectPackageManager(depsDir)
+	}
+	if cfg.NodeVersion == "" {
+		cfg.NodeVersion = DetectNodeVersion(cfg.AppDir)
+	}
+	if cfg.BuildContext == "" || cfg.WorkspaceDir != "" {
+		cfg.BuildContext = "."
+	}
+
+	data := dockerTemplateData{
+		PackageManager: cfg.PackageManager,
+		NodeVersion:    cfg.NodeVersion,
+		BuildContext:   cfg.BuildContext,
+		WorkspaceDir:   cfg.WorkspaceDir,
+		ImageName:      imageName(cfg.AppDir),
+	}
+	data.DependencyFiles, data.InstallCommand, data.BuildCommand = packageManagerCommands(depsDir, cfg.PackageManager, !cfg.LockfileStale)
+	if cfg.WorkspaceDir != "" && cfg.PackageManager == codemod.PackageManagerPnpm {
+		data.DependencyFiles += " pnpm-workspace.yaml*"
+	}
+	if cfg.LockfileStale {
+		logger.Warning("The lockfile does not match package.json, the Dockerfile installs without freezing it", "packageManager", cfg.PackageManager)
+	}
+	if cfg.PackageManager == codemod.PackageManagerYarn && codemod.IsYarnBerry(depsDir) {
+		if info, err := os.Stat(filepath.Join(depsDir, ".yarn")); err == nil && info.IsDir() {
+			data.HasYarnDir = true
+		}
+	}
+	if info, err := os.Stat(filepath.Join(cfg.AppDir, "public")); err == nil && info.IsDir() {
+		data.HasPublicDir = true
+	}
+
+	// Docker reads .dockerignore from the build context, a workspace build needs the one next to the Dockerfile
+	ignoreFile := ".dockerignore"
+	if cfg.WorkspaceDir != "" {
+		ignoreFile = "Dockerfile.dockerignore"
+	}
+	files := []struct {
+		template string
+		name     string
+	}{
+		{"Dockerfile.tmpl", "Dockerfile"},
+		{"dockerignore.tmpl", ignoreFile},
+	}
+
+	written := []string{}
+	for _, f := range files {
+		target := filepath.Join(cfg.AppDir, f.name)
+		if _, err := os.Stat(target); err == nil && !cfg.Force {
+			logger.Warning("File already exists, skipping generation", "path", target)
+			continue
+		}
+
+		content, err := renderTemplate(f.template, data)
+		if err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return written, fmt.Errorf("error writing %s: %w", target, err)
+		}
+
+		logger.Debug("Generated file", "path", target)
+		written = append(written, target)
+	}
+
+	return written, nil
+}
+
+func renderTemplate(name string, data interface{}) ([]byte, error) {
+	tmpl, err := template.ParseFS(templatesFS, "templates/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error rendering template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// packageManagerCommands returns the files needed to install dependencies, and the install and build commands.
+// frozen makes the install fail when the lockfile does not match package.json
+func packageManagerCommands(dir string, pm codemod.PackageManager, frozen bool) (string, string, string) {
+	hasFile := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	install := func(command, frozenFlag string) string {
+		if frozen {
+			return command + " " + frozenFlag
+		}
+		return command
+	}
+
+	switch pm {
+	case codemod.PackageManagerPnpm:
+		return "package.json pnpm-lock.yaml*", "corepack enable pnpm && " + install("pnpm install", "--frozen-lockfile"), "corepack enable pnpm && pnpm run build"
+	case codemod.PackageManagerYarn:
+		if codemod.IsYarnBerry(dir) {
+			return "package.json yarn.lock* .yarnrc.yml*", "corepack enable && " + install("yarn install", "--immutable"), "corepack enable && yarn run build"
+		}
+		return "package.json yarn.lock*", "corepack enable && " + install("yarn install", "--frozen-lockfile"), "corepack enable && yarn run build"
+	case codemod.PackageManagerBun:
+		return "package.json bun.lock*", "npm install -g bun && " + install("bun install", "--frozen-lockfile"), "npm install -g bun && bun run build"
+	}
+
+	if hasFile("package-lock.json") || hasFile("npm-shrinkwrap.json") {
+		if !frozen {
+			return "package.json package-lock.json* npm-shrinkwrap.json*", "npm install", "npm run build"
+		}
+		return "package.json package-lock.json* npm-shrinkwrap.json*", "npm ci", "npm run build"
+	}
+	return "package.json", "npm install", "npm run build"
+}
+
+// DetectNodeVersion reads the Node.js version from .nvmrc, .node-version or the
+// "engines.node" field of package.json, returning a tag usable with the node image
+func DetectNodeVersion(dir string) string {
+	for _, name := range []string{".nvmrc", ".node-version"} {
+		src, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if version := nodeImageTag(strings.TrimSpace(string(src))); version != "" {
+			return version
+		}
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return DefaultNodeVersion
+	}
+	var pkg struct {
+		Engines struct {
+			Node string `json:"node"`
+		} `json:"engines"`
+	}
+	if err := json.Unmarshal(src, &pkg); err != nil || pkg.Engines.Node == "" {
+		return DefaultNodeVersion
+	}
+	return engineRangeToVersion(pkg.Engines.Node)
+}
+
+var versionPattern = regexp.MustCompile(`^v?(\d+(\.\d+){0,2})$`)
+
+// nodeImageTag converts an .nvmrc entry such as "v20.11.0", "lts/iron" or "lts/*" to an image tag
+func nodeImageTag(version string) string {
+	switch {
+	case version == "":
+		return ""
+	case version == "node" || version == "stable":
+		return "current"
+	case version == "lts/*":
+		return "lts"
+	case strings.HasPrefix(version, "lts/"):
+		return strings.ToLower(strings.TrimPrefix(version, "lts/"))
+	}
+	if m := versionPattern.FindStringSubmatch(version); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+var majorPattern = regexp.MustCompile(`\d+`)
+
+// engineRangeToVersion picks a major version satisfying an engines range such as "^20.0.0" or ">=18.18.0"
+func engineRangeToVersion(constraint string) string {
+	constraint = strings.TrimSpace(strings.Split(constraint, "||")[0])
+	major := majorPattern.FindString(constraint)
+	if major == "" {
+		return DefaultNodeVersion
+	}
+
+	if strings.HasPrefix(constraint, ">") {
+		minMajor, _ := strconv.Atoi(major)
+		defaultMajor, _ := strconv.Atoi(DefaultNodeVersion)
+		if minMajor <= defaultMajor {
+			return DefaultNodeVersion
+		}
+	}
+	return major
+}
+
+func imageName(dir string) string {
+	src, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err == nil {
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(src, &pkg) == nil && pkg.Name != "" {
+			return strings.TrimPrefix(strings.ReplaceAll(pkg.Name, "@", ""), "/")
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "app"
+	}
+	return strings.ToLower(filepath.Base(abs))
+}
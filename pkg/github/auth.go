@@ -1,11 +1,11 @@
 package github
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
-
-	"github.com/cli/go-gh"
 )
 
 type AuthStatus struct {
@@ -15,20 +15,19 @@ type AuthStatus struct {
 	Error           error
 }
 
-func CheckAuthStatus() AuthStatus {
+func CheckAuthStatus(ctx context.Context) AuthStatus {
 	status := AuthStatus{
 		CliInstalled:    false,
 		IsAuthenticated: false,
 	}
 
-	_, err := exec.LookPath("gh")
-	if err != nil {
+	stdout, stderr, err := ExecContext(ctx, "auth", "status")
+	if errors.Is(err, exec.ErrNotFound) {
 		status.Error = fmt.Errorf("GitHub CLI ('gh') not found in PATH. Please install it: https://cli.github.com/")
 		return status
 	}
 	status.CliInstalled = true
 
-	stdout, stderr, err := gh.Exec("auth", "status")
 	if err != nil {
 		errMsg := stderr.String()
 		if strings.Contains(errMsg, "could not determine authentication status") || strings.Contains(errMsg, "No accounts logged in") {
@@ -1,13 +1,14 @@
 package github
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/runner"
 )
 
 type BranchOptions struct {
@@ -17,7 +18,7 @@ type BranchOptions struct {
 	SkipPull   bool
 }
 
-func CreateBranch(opts BranchOptions) (string, error) {
+func CreateBranch(ctx context.Context, opts BranchOptions) (string, error) {
 	logger := logging.GetLogger()
 
 	timestamp := time.Now().Unix()
@@ -28,8 +29,7 @@ func CreateBranch(opts BranchOptions) (string, error) {
 
 	logger.Debug(fmt.Sprintf("Ensuring branch %s exists based on %s (local: %t)", finalBranchName, opts.BaseBranch, opts.SkipPull))
 
-	checkoutBaseCmd := exec.Command("git", "-C", opts.Path, "checkout", opts.BaseBranch)
-	output, err := checkoutBaseCmd.CombinedOutput()
+	output, err := gitCombinedOutput(ctx, opts.Path, "checkout", opts.BaseBranch)
 	if err != nil {
 		errMsg := string(output)
 		isSuccessMsg := strings.Contains(errMsg, fmt.Sprintf("Already on '%s'", opts.BaseBranch)) ||
@@ -44,18 +44,16 @@ func CreateBranch(opts BranchOptions) (string, error) {
 
 	if !opts.SkipPull {
 		logger.Debug(fmt.Sprintf("Pulling latest changes for %s", opts.BaseBranch))
-		pullCmd := exec.Command("git", "-C", opts.Path, "pull", "origin", opts.BaseBranch)
-		if pullOutput, pullErr := pullCmd.CombinedOutput(); pullErr != nil {
+		if pullOutput, pullErr := gitCombinedOutput(ctx, opts.Path, "pull", "origin", opts.BaseBranch); pullErr != nil {
 			logger.Error(fmt.Sprintf("Failed to pull latest changes for %s: %s", opts.BaseBranch, pullErr))
 			logger.Error(string(pullOutput))
-			return "", fmt.Errorf("failed to pull base branch %s: %w", opts.BaseBranch, pullErr)
+			return "", fmt.Errorf("failed to pull base branch %s: %w", opts.BaseBranch, commandError(ctx, pullErr))
 		}
 	} else {
 		logger.Debug("Skipping pull step; operations remain local.")
 	}
 
-	createBranchCmd := exec.Command("git", "-C", opts.Path, "checkout", "-b", finalBranchName)
-	createOutput, createErr := createBranchCmd.CombinedOutput()
+	createOutput, createErr := gitCombinedOutput(ctx, opts.Path, "checkout", "-b", finalBranchName)
 
 	if createErr == nil {
 		logger.Debug(fmt.Sprintf("Branch %s created and checked out successfully.", finalBranchName))
@@ -67,8 +65,7 @@ func CreateBranch(opts BranchOptions) (string, error) {
 		strings.Contains(createErrMsg, fmt.Sprintf("fatal: branch '%s' already exists.", finalBranchName)) {
 		logger.Debug(fmt.Sprintf("Branch %s already exists, switching to it.", finalBranchName))
 
-		switchCmd := exec.Command("git", "-C", opts.Path, "checkout", finalBranchName)
-		switchOutput, switchErr := switchCmd.CombinedOutput()
+		switchOutput, switchErr := gitCombinedOutput(ctx, opts.Path, "checkout", finalBranchName)
 		switchMsg := string(switchOutput)
 
 		isSuccessMsg := strings.Contains(switchMsg, fmt.Sprintf("Already on '%s'", finalBranchName)) ||
@@ -80,21 +77,20 @@ func CreateBranch(opts BranchOptions) (string, error) {
 		} else {
 			logger.Debug(fmt.Sprintf("Failed to switch to existing branch %s: %s", finalBranchName, switchErr))
 			logger.Error(switchMsg)
-			return "", fmt.Errorf("branch '%s' exists but could not be checked out: %w", finalBranchName, switchErr)
+			return "", fmt.Errorf("branch '%s' exists but could not be checked out: %w", finalBranchName, commandError(ctx, switchErr))
 		}
 
 	}
 
 	logger.Error(fmt.Sprintf("Failed to create or checkout branch %s: %s", finalBranchName, createErr))
 	logger.Error(createErrMsg)
-	return "", fmt.Errorf("failed to create branch '%s': %w", finalBranchName, createErr)
+	return "", fmt.Errorf("failed to create branch '%s': %w", finalBranchName, commandError(ctx, createErr))
 }
 
 func GetCurrentBranch(path string) (string, error) {
 	logger := logging.GetLogger()
 
-	cmd := exec.Command("git", "-C", path, "branch", "--show-current")
-	output, err := cmd.CombinedOutput()
+	output, err := gitCombinedOutput(context.Background(), path, "branch", "--show-current")
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to get current branch: %s", err))
 		logger.Error(string(output))
@@ -105,34 +101,59 @@ func GetCurrentBranch(path string) (string, error) {
 	return branch, nil
 }
 
-func CommitChanges(path string, message string, files []string) error {
+// GetHeadCommit returns the full SHA of the commit checked out at path
+func GetHeadCommit(path string) (string, error) {
+	output, err := gitCombinedOutput(context.Background(), path, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD in %s: %s: %w", path, strings.TrimSpace(string(output)), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func CommitChanges(ctx context.Context, path string, message string, files []string) error {
+	if err := stageFiles(ctx, path, files); err != nil {
+		return err
+	}
+	return runCommit(ctx, path, nil, []string{"-m", message}, nil)
+}
+
+// stageFiles adds files to the index, or every change when files is empty
+func stageFiles(ctx context.Context, path string, files []string) error {
 	logger := logging.GetLogger()
 
-	var addCmd *exec.Cmd
+	args := []string{"add", "."}
 	if len(files) > 0 {
-		args := append([]string{"-C", path, "add"}, files...)
-		addCmd = exec.Command("git", args...)
-	} else {
-		addCmd = exec.Command("git", "-C", path, "add", ".")
+		args = append([]string{"add"}, files...)
 	}
 
-	if output, err := addCmd.CombinedOutput(); err != nil {
+	if output, err := gitCombinedOutput(ctx, path, args...); err != nil {
 		logger.Error(fmt.Sprintf("Failed to stage changes: %s", err))
 		logger.Error(string(output))
-		return err
+		return commandError(ctx, err)
 	}
+	return nil
+}
+
+// runCommit commits the index, passing configArgs before and commitArgs after the commit subcommand
+func runCommit(ctx context.Context, path string, configArgs []string, commitArgs []string, env []string) error {
+	logger := logging.GetLogger()
+
+	args := append(append([]string{}, configArgs...), "commit")
+	args = append(args, commitArgs...)
 
-	commitCmd := exec.Command("git", "-C", path, "commit", "-m", message)
-	output, err := commitCmd.CombinedOutput()
+	commitCmd := gitCommand(path, args...)
+	commitCmd.Env = env
+	result, err := runner.Run(ctx, commitCmd)
+	output := result.Combined()
 	if err != nil {
-		if strings.Contains(string(output), "nothing to commit") {
+		if strings.Contains(string(output), "nothing to commit") || strings.Contains(string(output), "no changes added to commit") {
 			logger.Info("No changes to commit")
 			return nil
 		}
 
 		logger.Error(fmt.Sprintf("Failed to commit changes: %s", err))
 		logger.Error(string(output))
-		return err
+		return commandError(ctx, err)
 	}
 
 	if logger.IsVerbose() {
@@ -142,7 +163,7 @@ func CommitChanges(path string, message string, files []string) error {
 	return nil
 }
 
-func PushBranch(path string, branch string, remote string) error {
+func PushBranch(ctx context.Context, path string, branch string, remote string) error {
 	logger := logging.GetLogger()
 
 	if remote == "" {
@@ -151,12 +172,11 @@ func PushBranch(path string, branch string, remote string) error {
 
 	logger.Debug(fmt.Sprintf("Pushing branch %s to %s", branch, remote))
 
-	pushCmd := exec.Command("git", "-C", path, "push", "-u", remote, branch)
-	output, err := pushCmd.CombinedOutput()
+	output, err := gitCombinedOutput(ctx, path, "push", "-u", remote, branch)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to push changes: %s", err))
 		logger.Error(string(output))
-		return err
+		return commandError(ctx, err)
 	}
 
 	if logger.IsVerbose() {
@@ -181,3 +201,38 @@ func containsTimestamp(branchName string) bool {
 	_, err := strconv.ParseInt(lastPart, 10, 64)
 	return err == nil
 }
+
+// DetectDefaultBranch returns the default branch of the repository at path. It follows
+// origin/HEAD, then init.defaultBranch, then a local main or master branch, and falls back to main.
+func DetectDefaultBranch(path string) string {
+	logger := logging.GetLogger()
+
+	if output, err := gitOutput(context.Background(), path, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		if branch := strings.TrimPrefix(strings.TrimSpace(string(output)), "origin/"); branch != "" {
+			logger.Debug("Detected default branch from origin/HEAD", "branch", branch)
+			return branch
+		}
+	}
+
+	if output, err := gitOutput(context.Background(), path, "config", "--get", "init.defaultBranch"); err == nil {
+		if branch := strings.TrimSpace(string(output)); branch != "" && BranchExists(path, branch) {
+			logger.Debug("Detected default branch from init.defaultBranch", "branch", branch)
+			return branch
+		}
+	}
+
+	for _, branch := range []string{"main", "master"} {
+		if BranchExists(path, branch) {
+			logger.Debug("Detected default branch from local branches", "branch", branch)
+			return branch
+		}
+	}
+
+	return "main"
+}
+
+// BranchExists reports whether the local branch exists in the repository at path
+func BranchExists(path string, branch string) bool {
+	_, err := gitOutput(context.Background(), path, "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
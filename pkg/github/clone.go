@@ -1,13 +1,15 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/blazity/enterprise-cli/pkg/logging"
-	"github.com/cli/go-gh"
+	"github.com/blazity/enterprise-cli/pkg/runner"
 )
 
 type CloneOptions struct {
@@ -17,10 +19,9 @@ type CloneOptions struct {
 	Depth       int
 }
 
-func CloneRepository(opts CloneOptions) error {
+func CloneRepository(ctx context.Context, opts CloneOptions) error {
 	logger := logging.GetLogger()
 
-
 	args := []string{"repo", "clone"}
 
 	repoName := opts.Repository
@@ -42,11 +43,18 @@ func CloneRepository(opts CloneOptions) error {
 	logger.Debug("Assembling git arguments for clone...")
 	extraArgs := []string{}
 
-	if opts.Branch != "" {
+	// Commit SHAs cannot be passed to -b, so those are checked out after a full clone
+	checkoutCommit, err := isRemoteCommit(ctx, repoName, opts.Branch)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to resolve %s in %s: %s", opts.Branch, repoName, err))
+		return err
+	}
+
+	if opts.Branch != "" && !checkoutCommit {
 		extraArgs = append(extraArgs, "-b", opts.Branch)
 	}
 
-	if opts.Depth > 0 {
+	if opts.Depth > 0 && !checkoutCommit {
 		extraArgs = append(extraArgs, "--depth", fmt.Sprintf("%d", opts.Depth))
 	}
 
@@ -55,7 +63,7 @@ func CloneRepository(opts CloneOptions) error {
 		args = append(args, extraArgs...)
 	}
 
-	stdout, stderr, err := gh.Exec(args...)
+	stdout, stderr, err := ExecContext(ctx, args...)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to clone repository: %s", err))
 		logger.Error(stderr.String())
@@ -66,7 +74,55 @@ func CloneRepository(opts CloneOptions) error {
 		logger.Debug(stdout.String())
 	}
 
+	if checkoutCommit {
+		logger.Debug(fmt.Sprintf("Checking out commit %s", opts.Branch))
+		if output, err := gitCombinedOutput(ctx, opts.Destination, "checkout", "--detach", opts.Branch); err != nil {
+			logger.Error(fmt.Sprintf("Failed to check out commit %s: %s", opts.Branch, err))
+			logger.Error(string(output))
+			return fmt.Errorf("failed to check out commit '%s': %w", opts.Branch, commandError(ctx, err))
+		}
+	}
+
 	logger.Info(fmt.Sprintf("Repository %s cloned successfully", opts.Repository))
 
 	return nil
 }
+
+// isRemoteCommit reports whether ref names a commit of repository rather than a branch or tag. Branches
+// and tags can look like SHAs too, so those are looked up with git ls-remote first
+func isRemoteCommit(ctx context.Context, repository string, ref string) (bool, error) {
+	if ref == "" || !IsCommitSHA(ref) {
+		return false, nil
+	}
+
+	result, err := runner.Run(ctx, gitCommand(".", "-c", "credential.helper=", "-c", "credential.helper=!gh auth git-credential",
+		"ls-remote", "--heads", "--tags", repositoryURL(repository), ref))
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, networkError(fmt.Errorf("failed to list the refs of %s: %w", repository, err), result.Stderr)
+	}
+	for _, line := range strings.Split(string(result.Stdout), "\n") {
+		_, name, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		if name == "refs/heads/"+ref || strings.TrimSuffix(name, "^{}") == "refs/tags/"+ref {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// repositoryURL returns the URL git fetches repository from, accepting owner/repo or a URL
+func repositoryURL(repository string) string {
+	if strings.HasPrefix(repository, "http") || strings.HasPrefix(repository, "git@") {
+		return repository
+	}
+	return "https://github.com/" + repository + ".git"
+}
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// IsCommitSHA reports whether ref looks like an abbreviated or full commit SHA
+func IsCommitSHA(ref string) bool {
+	return commitSHAPattern.MatchString(ref)
+}
@@ -0,0 +1,83 @@
+package github
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CodeOwnersFile is where the generated CODEOWNERS is written, relative to the repository root
+var CodeOwnersFile = filepath.Join(".github", "CODEOWNERS")
+
+// codeOwnersHeader marks the entries managed by the CLI
+const codeOwnersHeader = "# Managed by enterprise-cli: infrastructure and workflows"
+
+// WriteCodeOwners makes owners the code owners of paths in the CODEOWNERS of root. An existing file is kept,
+// and only the paths it does not assign yet are appended. It returns the written file, or an empty string
+// when nothing had to change
+func WriteCodeOwners(root string, paths []string, owners []string) (string, error) {
+	if len(owners) == 0 || len(paths) == 0 {
+		return "", nil
+	}
+
+	normalized := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		owner = strings.TrimSpace(owner)
+		if owner == "" {
+			continue
+		}
+		if !strings.HasPrefix(owner, "@") && !strings.Contains(owner, "@") {
+			owner = "@" + owner
+		}
+		normalized = append(normalized, owner)
+	}
+	if len(normalized) == 0 {
+		return "", nil
+	}
+
+	target := filepath.Join(root, CodeOwnersFile)
+	existing, err := os.ReadFile(target)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %w", CodeOwnersFile, err)
+	}
+
+	assigned := map[string]bool{}
+	for _, line := range strings.Split(string(existing), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && !strings.HasPrefix(fields[0], "#") {
+			assigned[fields[0]] = true
+		}
+	}
+
+	var entries []string
+	for _, path := range paths {
+		pattern := "/" + strings.Trim(filepath.ToSlash(path), "/") + "/"
+		if assigned[pattern] {
+			continue
+		}
+		entries = append(entries, fmt.Sprintf("%s %s", pattern, strings.Join(normalized, " ")))
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+
+	var content strings.Builder
+	content.Write(existing)
+	if len(existing) > 0 {
+		if !strings.HasSuffix(string(existing), "\n") {
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+	}
+	content.WriteString(codeOwnersHeader + "\n")
+	content.WriteString(strings.Join(entries, "\n") + "\n")
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(target, []byte(content.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", CodeOwnersFile, err)
+	}
+	return CodeOwnersFile, nil
+}
@@ -0,0 +1,226 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"text/template"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+)
+
+// DefaultCommitTemplate prefixes the message with the ticket, when one is set
+const DefaultCommitTemplate = "{{with .Ticket}}{{.}} {{end}}{{.Message}}"
+
+// CommitOptions customizes the commits created while preparing a repository
+type CommitOptions struct {
+	// Sign signs every commit, with SigningKey and SigningFormat (openpgp, ssh or x509) when set,
+	// and with the user's git configuration otherwise
+	Sign          bool
+	SigningFormat string
+	SigningKey    string
+	// Author is the "Name <email>" identity commits are authored and committed as
+	Author string
+	// MessageTemplate is a text/template rendering the commit message from CommitMessageData
+	MessageTemplate string
+	Ticket          string
+	// Squash folds every step into a single commit once Finish is called
+	Squash bool
+}
+
+// CommitMessageData is available to commit message templates
+type CommitMessageData struct {
+	// Step identifies the preparation step, such as "terraform" or "move"
+	Step     string
+	Provider string
+	Ticket   string
+	// Message is the default message of the step
+	Message string
+}
+
+// Committer creates the commits of a preparation according to CommitOptions
+type Committer struct {
+	opts     CommitOptions
+	provider string
+	tmpl     *template.Template
+	name     string
+	email    string
+	base     string
+	messages []string
+}
+
+// NewCommitter validates opts and returns a Committer for provider
+func NewCommitter(provider string, opts CommitOptions) (*Committer, error) {
+	c := &Committer{opts: opts, provider: provider}
+
+	text := opts.MessageTemplate
+	if text == "" {
+		text = DefaultCommitTemplate
+	}
+	tmpl, err := template.New("commit").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
+	c.tmpl = tmpl
+
+	if opts.Author != "" {
+		address, err := mail.ParseAddress(opts.Author)
+		if err != nil || address.Name == "" {
+			return nil, fmt.Errorf("invalid author '%s', expected \"Name <email>\"", opts.Author)
+		}
+		c.name, c.email = address.Name, address.Address
+	}
+
+	switch opts.SigningFormat {
+	case "", "openpgp", "ssh", "x509":
+	default:
+		return nil, fmt.Errorf("unknown signing format '%s', expected openpgp, ssh or x509", opts.SigningFormat)
+	}
+	if (opts.SigningFormat != "" || opts.SigningKey != "") && !opts.Sign {
+		c.opts.Sign = true
+	}
+
+	// Validate the template up front rather than after the first step
+	if _, err := c.message("step", "message"); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Start records the commit the preparation starts from, which Finish squashes onto and Created lists from
+func (c *Committer) Start(ctx context.Context, path string) error {
+	base, err := GetHeadCommit(path)
+	if err != nil {
+		if !c.opts.Squash {
+			// Only the list of created commits is lost
+			return nil
+		}
+		return err
+	}
+	c.base = base
+	return nil
+}
+
+// Commit stages files, or every change when files is empty, and commits them for step
+func (c *Committer) Commit(ctx context.Context, path string, step string, message string, files []string) error {
+	if err := stageFiles(ctx, path, files); err != nil {
+		return err
+	}
+	return c.CommitStaged(ctx, path, step, message)
+}
+
+// CommitStaged commits the index as it is for step
+func (c *Committer) CommitStaged(ctx context.Context, path string, step string, message string) error {
+	rendered, err := c.message(step, message)
+	if err != nil {
+		return err
+	}
+	c.messages = append(c.messages, rendered)
+
+	if c.opts.Squash {
+		// Intermediate commits are folded by Finish, so they are neither signed nor attributed
+		return runCommit(ctx, path, nil, []string{"--no-gpg-sign", "-m", rendered}, nil)
+	}
+	return c.run(ctx, path, rendered)
+}
+
+// Finish squashes the commits made since Start into a single commit for step, when squashing
+func (c *Committer) Finish(ctx context.Context, path string, step string, message string) error {
+	if !c.opts.Squash || c.base == "" {
+		return nil
+	}
+
+	head, err := GetHeadCommit(path)
+	if err != nil {
+		return err
+	}
+	if head == c.base {
+		return nil
+	}
+
+	if output, err := gitCombinedOutput(ctx, path, "reset", "--soft", c.base); err != nil {
+		logging.GetLogger().Error(string(output))
+		return fmt.Errorf("failed to squash commits: %w", commandError(ctx, err))
+	}
+
+	rendered, err := c.message(step, message)
+	if err != nil {
+		return err
+	}
+	body := make([]string, 0, len(c.messages))
+	for _, m := range c.messages {
+		body = append(body, "- "+m)
+	}
+	return c.run(ctx, path, rendered+"\n\n"+strings.Join(body, "\n"))
+}
+
+// CreatedCommit is a commit made by a Committer
+type CreatedCommit struct {
+	SHA     string `json:"sha" yaml:"sha"`
+	Subject string `json:"subject" yaml:"subject"`
+}
+
+// Created lists the commits made since Start, oldest first
+func (c *Committer) Created(ctx context.Context, path string) ([]CreatedCommit, error) {
+	if c.base == "" {
+		return nil, nil
+	}
+
+	output, err := gitOutput(ctx, path, "log", "--reverse", "--format=%H%x09%s", c.base+"..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the created commits: %w", commandError(ctx, err))
+	}
+
+	var commits []CreatedCommit
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		sha, subject, _ := strings.Cut(line, "\t")
+		commits = append(commits, CreatedCommit{SHA: sha, Subject: subject})
+	}
+	return commits, nil
+}
+
+func (c *Committer) message(step string, message string) (string, error) {
+	var buf bytes.Buffer
+	data := CommitMessageData{
+		Step:     step,
+		Provider: c.provider,
+		Ticket:   c.opts.Ticket,
+		Message:  message,
+	}
+	if err := c.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render commit message: %w", err)
+	}
+	rendered := strings.TrimSpace(buf.String())
+	if rendered == "" {
+		return "", fmt.Errorf("commit message template rendered an empty message for step %s", step)
+	}
+	return rendered, nil
+}
+
+func (c *Committer) run(ctx context.Context, path string, message string) error {
+	var configArgs, commitArgs, env []string
+
+	if c.opts.SigningFormat != "" {
+		configArgs = append(configArgs, "-c", "gpg.format="+c.opts.SigningFormat)
+	}
+	if c.opts.Sign {
+		if c.opts.SigningKey != "" {
+			commitArgs = append(commitArgs, "--gpg-sign="+c.opts.SigningKey)
+		} else {
+			commitArgs = append(commitArgs, "--gpg-sign")
+		}
+	}
+	if c.name != "" {
+		commitArgs = append(commitArgs, "--author", fmt.Sprintf("%s <%s>", c.name, c.email))
+		env = append(env, "GIT_COMMITTER_NAME="+c.name, "GIT_COMMITTER_EMAIL="+c.email)
+	}
+	commitArgs = append(commitArgs, "-m", message)
+
+	return runCommit(ctx, path, configArgs, commitArgs, env)
+}
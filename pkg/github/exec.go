@@ -0,0 +1,101 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/runner"
+	"github.com/cli/go-gh"
+	"github.com/cli/go-gh/pkg/api"
+)
+
+// ExecContext runs gh with args like gh.Exec through the configured runner, stopping it when ctx is done
+func ExecContext(ctx context.Context, args ...string) (stdout, stderr bytes.Buffer, err error) {
+	result, err := runner.Run(ctx, runner.Command{Name: "gh", Args: args})
+	stdout.Write(result.Stdout)
+	stderr.Write(result.Stderr)
+
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	case errors.Is(err, exec.ErrNotFound):
+		err = fmt.Errorf("could not find gh executable in PATH. error: %w", err)
+	default:
+		err = networkError(fmt.Errorf("failed to run gh: %s. error: %w", stderr.String(), err), stderr.Bytes())
+	}
+	return
+}
+
+// ErrNetwork is wrapped by the errors of git and gh commands that could not reach GitHub
+var ErrNetwork = errors.New("github is unreachable")
+
+var networkErrorPatterns = []string{
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"no such host",
+	"failed to connect",
+	"connection refused",
+	"connection timed out",
+	"network is unreachable",
+	"i/o timeout",
+	"tls handshake timeout",
+	"error connecting to",
+}
+
+// networkError wraps err with ErrNetwork when the output of the failed command shows the remote could
+// not be reached, as opposed to a missing repository or ref, or failed authentication
+func networkError(err error, output []byte) error {
+	lower := strings.ToLower(string(output))
+	for _, pattern := range networkErrorPatterns {
+		if strings.Contains(lower, pattern) {
+			return fmt.Errorf("%w: %w", ErrNetwork, err)
+		}
+	}
+	return err
+}
+
+// RESTClient returns a GitHub REST API client using the gh authentication, whose requests go through
+// the configured runner
+func RESTClient() (api.RESTClient, error) {
+	r := runner.Get()
+	opts := &api.ClientOptions{Transport: r.Transport(http.DefaultTransport)}
+	if r.Offline() {
+		// Replayed requests need no credentials, so the gh configuration is not consulted
+		opts.Host = "github.com"
+		opts.AuthToken = "offline"
+	}
+	return gh.RESTClient(opts)
+}
+
+// gitCommand returns a git command run in the repository at path
+func gitCommand(path string, args ...string) runner.Command {
+	return runner.Command{Name: "git", Args: append([]string{"-C", path}, args...)}
+}
+
+// gitOutput runs git in the repository at path and returns its standard output, like exec.Cmd.Output
+func gitOutput(ctx context.Context, path string, args ...string) ([]byte, error) {
+	result, err := runner.Run(ctx, gitCommand(path, args...))
+	return result.Stdout, err
+}
+
+// gitCombinedOutput runs git in the repository at path and returns its standard output and error,
+// like exec.Cmd.CombinedOutput
+func gitCombinedOutput(ctx context.Context, path string, args ...string) ([]byte, error) {
+	result, err := runner.Run(ctx, gitCommand(path, args...))
+	return result.Combined(), err
+}
+
+// commandError returns ctx.Err() when a command failed because ctx was done, so callers can tell
+// a cancellation from a failure, and err otherwise
+func commandError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
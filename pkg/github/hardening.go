@@ -0,0 +1,328 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/cli/go-gh/pkg/api"
+	"gopkg.in/yaml.v3"
+)
+
+// HardeningOptions configures the settings applied to a newly created repository
+type HardeningOptions struct {
+	// ProtectBranch protects the default branch against force pushes and deletion, requiring
+	// RequiredChecks to pass and RequiredReviews approvals before merging
+	ProtectBranch   bool
+	RequiredChecks  []string
+	RequiredReviews int
+	// Environments are created as GitHub Environments, the ones also listed in ProtectedEnvironments
+	// requiring a review from EnvironmentReviewers, given as user logins or org/team slugs
+	Environments          []string
+	ProtectedEnvironments []string
+	EnvironmentReviewers  []string
+	// CodeOwners own the infrastructure and workflow directories in the generated CODEOWNERS
+	CodeOwners []string
+	// Teams grants org/team slugs access to the repository, as "org/team:permission"
+	Teams []string
+}
+
+// HardeningResult lists the settings that were applied and the ones that failed
+type HardeningResult struct {
+	Applied []string
+	Failed  []string
+}
+
+// teamPermissions are the repository permissions a team can be granted
+var teamPermissions = []string{"pull", "triage", "push", "maintain", "admin"}
+
+// TeamAccess is a team granted a permission on a repository
+type TeamAccess struct {
+	Org        string
+	Slug       string
+	Permission string
+}
+
+// ParseTeamAccess parses "org/team[:permission]", defaulting to the push permission
+func ParseTeamAccess(s string) (TeamAccess, error) {
+	team, permission, found := strings.Cut(s, ":")
+	if !found {
+		permission = "push"
+	}
+	org, slug, ok := strings.Cut(team, "/")
+	if !ok || org == "" || slug == "" {
+		return TeamAccess{}, fmt.Errorf("invalid team '%s', expected org/team[:permission]", s)
+	}
+	for _, p := range teamPermissions {
+		if permission == p {
+			return TeamAccess{Org: org, Slug: slug, Permission: permission}, nil
+		}
+	}
+	return TeamAccess{}, fmt.Errorf("unknown permission '%s' for team %s, expected one of: %s", permission, team, strings.Join(teamPermissions, ", "))
+}
+
+// Validate checks the options before anything is created
+func (o HardeningOptions) Validate() error {
+	if o.RequiredReviews < 0 || o.RequiredReviews > 6 {
+		return fmt.Errorf("required reviews must be between 0 and 6, got %d", o.RequiredReviews)
+	}
+	for _, team := range o.Teams {
+		if _, err := ParseTeamAccess(team); err != nil {
+			return err
+		}
+	}
+	if len(o.EnvironmentReviewers) > 6 {
+		return fmt.Errorf("environments accept at most 6 reviewers, got %d", len(o.EnvironmentReviewers))
+	}
+	return nil
+}
+
+// HardenRepository applies opts to repo, whose default branch is branch. Every setting is attempted
+// even when an earlier one fails, as some require a paid plan for private repositories
+func HardenRepository(ctx context.Context, repo string, branch string, opts HardeningOptions) HardeningResult {
+	logger := logging.GetLogger()
+	var result HardeningResult
+
+	apply := func(name string, fn func() error) {
+		if err := fn(); err != nil {
+			logger.Warning(fmt.Sprintf("Failed to apply %s", name), "repository", repo, "error", err)
+			result.Failed = append(result.Failed, name)
+			return
+		}
+		logger.Debug("Applied repository setting", "setting", name, "repository", repo)
+		result.Applied = append(result.Applied, name)
+	}
+
+	if opts.ProtectBranch {
+		apply("branch protection on "+branch, func() error {
+			return ProtectBranch(ctx, repo, branch, opts.RequiredChecks, opts.RequiredReviews, len(opts.CodeOwners) > 0)
+		})
+	}
+
+	for _, env := range opts.Environments {
+		env := env
+		var reviewers []string
+		if containsString(opts.ProtectedEnvironments, env) {
+			reviewers = opts.EnvironmentReviewers
+		}
+		apply("environment "+env, func() error {
+			return CreateEnvironment(ctx, repo, env, reviewers, opts.ProtectBranch)
+		})
+	}
+
+	for _, team := range opts.Teams {
+		access, _ := ParseTeamAccess(team)
+		apply(fmt.Sprintf("%s access for %s/%s", access.Permission, access.Org, access.Slug), func() error {
+			return GrantTeamAccess(ctx, repo, access)
+		})
+	}
+
+	return result
+}
+
+// ProtectBranch requires checks and reviews before merging into branch, and forbids force pushes and deletion
+func ProtectBranch(ctx context.Context, repo string, branch string, checks []string, reviews int, codeOwnerReviews bool) error {
+	body := map[string]interface{}{
+		"required_status_checks":        nil,
+		"enforce_admins":                false,
+		"required_pull_request_reviews": nil,
+		"restrictions":                  nil,
+		"allow_force_pushes":            false,
+		"allow_deletions":               false,
+		"required_linear_history":       false,
+	}
+	if len(checks) > 0 {
+		body["required_status_checks"] = map[string]interface{}{
+			"strict":   true,
+			"contexts": checks,
+		}
+	}
+	if reviews > 0 {
+		body["required_pull_request_reviews"] = map[string]interface{}{
+			"dismiss_stale_reviews":           true,
+			"require_code_owner_reviews":      codeOwnerReviews,
+			"required_approving_review_count": reviews,
+		}
+	}
+
+	return restRequest(ctx, "PUT", fmt.Sprintf("repos/%s/branches/%s/protection", repo, url.PathEscape(branch)), body, nil)
+}
+
+// CreateEnvironment creates or updates the environment name. When reviewers are given, deployments to it
+// wait for one of them to approve, and when protectedBranches is set only protected branches may deploy
+func CreateEnvironment(ctx context.Context, repo string, name string, reviewers []string, protectedBranches bool) error {
+	body := map[string]interface{}{}
+
+	if len(reviewers) > 0 {
+		owner, _, _ := strings.Cut(repo, "/")
+		var entries []map[string]interface{}
+		for _, reviewer := range reviewers {
+			entry, err := resolveReviewer(ctx, owner, reviewer)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		body["reviewers"] = entries
+	}
+	if protectedBranches {
+		body["deployment_branch_policy"] = map[string]interface{}{
+			"protected_branches":     true,
+			"custom_branch_policies": false,
+		}
+	}
+
+	return restRequest(ctx, "PUT", fmt.Sprintf("repos/%s/environments/%s", repo, url.PathEscape(name)), body, nil)
+}
+
+// DeleteEnvironment deletes the environment name with its secrets and variables, succeeding when it is already gone
+func DeleteEnvironment(ctx context.Context, repo string, name string) error {
+	err := restRequest(ctx, "DELETE", fmt.Sprintf("repos/%s/environments/%s", repo, url.PathEscape(name)), nil, nil)
+	var httpErr api.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// GrantTeamAccess gives a team the permission in access on repo
+func GrantTeamAccess(ctx context.Context, repo string, access TeamAccess) error {
+	body := map[string]interface{}{"permission": access.Permission}
+	return restRequest(ctx, "PUT", fmt.Sprintf("orgs/%s/teams/%s/repos/%s", access.Org, access.Slug, repo), body, nil)
+}
+
+// resolveReviewer turns a user login or an org/team slug into an environment reviewer
+func resolveReviewer(ctx context.Context, owner string, reviewer string) (map[string]interface{}, error) {
+	var response struct {
+		ID int64 `json:"id"`
+	}
+
+	reviewer = strings.TrimPrefix(reviewer, "@")
+	if org, slug, isTeam := strings.Cut(reviewer, "/"); isTeam {
+		if err := restRequest(ctx, "GET", fmt.Sprintf("orgs/%s/teams/%s", org, slug), nil, &response); err != nil {
+			return nil, fmt.Errorf("failed to look up team %s: %w", reviewer, err)
+		}
+		return map[string]interface{}{"type": "Team", "id": response.ID}, nil
+	}
+
+	if err := restRequest(ctx, "GET", fmt.Sprintf("users/%s", reviewer), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", reviewer, err)
+	}
+	return map[string]interface{}{"type": "User", "id": response.ID}, nil
+}
+
+// restRequest sends body as JSON to the GitHub REST API using the gh authentication
+func restRequest(ctx context.Context, method string, path string, body interface{}, response interface{}) error {
+	client, err := RESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub API client: %w", err)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	logging.GetLogger().Debug("GitHub API request", "method", method, "path", path)
+	return client.DoWithContext(ctx, method, path, reader, response)
+}
+
+// WorkflowChecks returns the check names of the jobs in the workflows of dir that run on pull requests,
+// which can be required by branch protection. Matrix jobs and jobs with computed names are left out
+func WorkflowChecks(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var checks []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var workflow struct {
+			On   yaml.Node `yaml:"on"`
+			Jobs map[string]struct {
+				Name     string                 `yaml:"name"`
+				Strategy map[string]interface{} `yaml:"strategy"`
+			} `yaml:"jobs"`
+		}
+		if err := yaml.Unmarshal(content, &workflow); err != nil {
+			logging.GetLogger().Debug("Skipping unparsable workflow", "file", entry.Name(), "error", err)
+			continue
+		}
+		if !triggersOn(&workflow.On, "pull_request") {
+			continue
+		}
+
+		for id, job := range workflow.Jobs {
+			if _, isMatrix := job.Strategy["matrix"]; isMatrix {
+				continue
+			}
+			name := job.Name
+			if name == "" {
+				name = id
+			}
+			if strings.Contains(name, "${{") || containsString(checks, name) {
+				continue
+			}
+			checks = append(checks, name)
+		}
+	}
+
+	sort.Strings(checks)
+	return checks, nil
+}
+
+// triggersOn reports whether the workflow "on" node lists event, in any of its string, list or map forms
+func triggersOn(on *yaml.Node, event string) bool {
+	switch on.Kind {
+	case yaml.ScalarNode:
+		return on.Value == event
+	case yaml.SequenceNode:
+		for _, item := range on.Content {
+			if item.Value == event {
+				return true
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(on.Content); i += 2 {
+			if on.Content[i].Value == event {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
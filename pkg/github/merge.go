@@ -0,0 +1,34 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/blazity/enterprise-cli/pkg/runner"
+)
+
+// MergeFile performs a three-way merge of the changes between base and other into current,
+// like `git merge-file`. It returns the merged content, which contains conflict markers
+// labelled with the given names when the number of conflicts is greater than zero.
+func MergeFile(ctx context.Context, current, base, other string, currentLabel, baseLabel, otherLabel string) ([]byte, int, error) {
+	result, err := runner.Run(ctx, runner.Command{
+		Name: "git",
+		Args: []string{"merge-file", "-p", "--diff3",
+			"-L", currentLabel, "-L", baseLabel, "-L", otherLabel,
+			current, base, other},
+	})
+	if err == nil {
+		return result.Stdout, 0, nil
+	}
+
+	var exitErr *runner.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode > 0 && exitErr.ExitCode < 128 {
+		return result.Stdout, exitErr.ExitCode, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, 0, ctx.Err()
+	}
+	return nil, 0, fmt.Errorf("failed to merge %s: %s: %w", current, result.Stderr, err)
+}
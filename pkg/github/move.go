@@ -0,0 +1,275 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/runner"
+)
+
+// UntrackedPolicy decides what MoveTrackedToSubDir does with files git does not track
+type UntrackedPolicy string
+
+const (
+	// UntrackedLeave leaves untracked and ignored files where they are
+	UntrackedLeave UntrackedPolicy = "leave"
+	// UntrackedMove also moves untracked files that are not ignored, without staging them
+	UntrackedMove UntrackedPolicy = "move"
+	// UntrackedMoveAll also moves untracked and ignored files, such as node_modules or .env.local
+	UntrackedMoveAll UntrackedPolicy = "move-all"
+)
+
+// UntrackedPolicies lists the valid untracked file policies
+var UntrackedPolicies = []UntrackedPolicy{UntrackedLeave, UntrackedMove, UntrackedMoveAll}
+
+// ParseUntrackedPolicy validates s, defaulting to UntrackedLeave when empty
+func ParseUntrackedPolicy(s string) (UntrackedPolicy, error) {
+	if s == "" {
+		return UntrackedLeave, nil
+	}
+	names := make([]string, 0, len(UntrackedPolicies))
+	for _, policy := range UntrackedPolicies {
+		if UntrackedPolicy(s) == policy {
+			return policy, nil
+		}
+		names = append(names, string(policy))
+	}
+	return "", fmt.Errorf("unknown untracked files policy '%s', expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// MoveResult reports what MoveTrackedToSubDir did, with paths relative to the repository root
+type MoveResult struct {
+	Moved     []string
+	Untracked []string
+	Ignored   []string
+	// LeftBehind are the untracked and ignored paths that were not moved because of the policy
+	LeftBehind []string
+}
+
+type indexEntry struct {
+	mode string
+	hash string
+	path string
+}
+
+// MoveTrackedToSubDir moves the tracked files of the repository at path into subDir, except the
+// top-level entries listed in keep, the way git mv does. Only the renames are staged, so committing
+// the index with CommitStaged produces a pure rename commit that git detects at 100% similarity,
+// and unstaged changes of the moved files stay unstaged. The index must not have staged changes.
+func MoveTrackedToSubDir(ctx context.Context, path string, subDir string, keep []string, policy UntrackedPolicy) (*MoveResult, error) {
+	logger := logging.GetLogger()
+
+	if subDir == "" || filepath.IsAbs(subDir) || strings.HasPrefix(filepath.Clean(subDir), "..") {
+		return nil, fmt.Errorf("target directory must be a subdirectory of the repository")
+	}
+	subDir = filepath.ToSlash(filepath.Clean(subDir))
+
+	if _, err := gitOutput(ctx, path, "diff", "--cached", "--quiet"); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("the git index has staged changes, commit or unstage them before moving files")
+	}
+
+	skip := func(rel string) bool {
+		top := strings.SplitN(strings.TrimSuffix(rel, "/"), "/", 2)[0]
+		if top == strings.SplitN(subDir, "/", 2)[0] {
+			return true
+		}
+		for _, entry := range keep {
+			if top == entry {
+				return true
+			}
+		}
+		return false
+	}
+
+	entries, err := listIndex(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := lsFiles(ctx, path, "--others", "--exclude-standard", "--directory")
+	if err != nil {
+		return nil, err
+	}
+	ignored, err := lsFiles(ctx, path, "--others", "--ignored", "--exclude-standard", "--directory")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MoveResult{}
+	var moves []indexEntry
+	for _, entry := range entries {
+		if skip(entry.path) {
+			continue
+		}
+		if entry.mode == "160000" {
+			return nil, fmt.Errorf("cannot move submodule %s, move it with git mv first", entry.path)
+		}
+		moves = append(moves, entry)
+	}
+	for _, rel := range untracked {
+		if !skip(rel) {
+			result.Untracked = append(result.Untracked, rel)
+		}
+	}
+	for _, rel := range ignored {
+		if !skip(rel) {
+			result.Ignored = append(result.Ignored, rel)
+		}
+	}
+
+	for _, entry := range moves {
+		dst := filepath.Join(path, subDir, entry.path)
+		if _, err := os.Lstat(dst); err == nil {
+			return nil, fmt.Errorf("cannot move %s, %s already exists", entry.path, filepath.Join(subDir, entry.path))
+		}
+	}
+
+	var renamed [][2]string
+	rollback := func() {
+		for i := len(renamed) - 1; i >= 0; i-- {
+			_ = os.MkdirAll(filepath.Dir(renamed[i][0]), 0755)
+			_ = os.Rename(renamed[i][1], renamed[i][0])
+		}
+		// The index is restored even when ctx was cancelled
+		_, _ = gitOutput(context.Background(), path, "reset", "-q")
+	}
+	rename := func(rel string) error {
+		src := filepath.Join(path, rel)
+		dst := filepath.Join(path, subDir, rel)
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			// Deleted in the working tree, only the index entry moves
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+		renamed = append(renamed, [2]string{src, dst})
+		return nil
+	}
+
+	var info bytes.Buffer
+	for _, entry := range moves {
+		fmt.Fprintf(&info, "0 %s\t%s\x00", strings.Repeat("0", len(entry.hash)), entry.path)
+		fmt.Fprintf(&info, "%s %s\t%s\x00", entry.mode, entry.hash, subDir+"/"+entry.path)
+	}
+	if len(moves) > 0 {
+		cmd := gitCommand(path, "update-index", "-z", "--index-info")
+		cmd.Stdin = info.Bytes()
+		if result, err := runner.Run(ctx, cmd); err != nil {
+			logger.Error(string(result.Combined()))
+			rollback()
+			return nil, fmt.Errorf("failed to stage renames: %w", commandError(ctx, err))
+		}
+	}
+
+	for _, entry := range moves {
+		if err := rename(entry.path); err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to move %s: %w", entry.path, err)
+		}
+		result.Moved = append(result.Moved, entry.path)
+	}
+
+	moveUntracked := func(paths []string, move bool) error {
+		for _, rel := range paths {
+			if !move {
+				result.LeftBehind = append(result.LeftBehind, rel)
+				continue
+			}
+			if err := rename(strings.TrimSuffix(rel, "/")); err != nil {
+				return fmt.Errorf("failed to move untracked %s: %w", rel, err)
+			}
+		}
+		return nil
+	}
+	if err := moveUntracked(result.Untracked, policy == UntrackedMove || policy == UntrackedMoveAll); err != nil {
+		rollback()
+		return nil, err
+	}
+	if err := moveUntracked(result.Ignored, policy == UntrackedMoveAll); err != nil {
+		rollback()
+		return nil, err
+	}
+
+	removeEmptyDirs(path, result.Moved)
+
+	logger.Debug(fmt.Sprintf("Moved %d tracked files to %s", len(result.Moved), subDir))
+	return result, nil
+}
+
+// listIndex returns the stage 0 entries of the index
+func listIndex(ctx context.Context, path string) ([]indexEntry, error) {
+	output, err := gitOutput(ctx, path, "ls-files", "-z", "--stage")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked files: %w", commandError(ctx, err))
+	}
+
+	var entries []indexEntry
+	for _, record := range strings.Split(string(output), "\x00") {
+		if record == "" {
+			continue
+		}
+		meta, file, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git ls-files output: %q", record)
+		}
+		if fields[2] != "0" {
+			return nil, fmt.Errorf("%s has unresolved merge conflicts", file)
+		}
+		entries = append(entries, indexEntry{mode: fields[0], hash: fields[1], path: file})
+	}
+	return entries, nil
+}
+
+func lsFiles(ctx context.Context, path string, args ...string) ([]string, error) {
+	output, err := gitOutput(ctx, path, append([]string{"ls-files", "-z"}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", commandError(ctx, err))
+	}
+
+	var files []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// removeEmptyDirs removes the directories of moved files that were left empty, deepest first
+func removeEmptyDirs(root string, moved []string) {
+	dirs := map[string]bool{}
+	for _, rel := range moved {
+		for dir := filepath.Dir(rel); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	for _, dir := range sorted {
+		// Remove fails on directories that still hold files, which is what we want
+		_ = os.Remove(filepath.Join(root, dir))
+	}
+}
+
+// CommitStaged commits the index as it is, without staging anything else
+func CommitStaged(ctx context.Context, path string, message string) error {
+	return runCommit(ctx, path, nil, []string{"-m", message}, nil)
+}
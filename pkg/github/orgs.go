@@ -1,18 +1,18 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/blazity/enterprise-cli/pkg/logging"
-	"github.com/cli/go-gh"
 )
 
-func GetOrganizations() ([]string, error) {
+func GetOrganizations(ctx context.Context) ([]string, error) {
 	logger := logging.GetLogger()
 	logger.Debug("Fetching organizations...")
 
-	stdout, stderr, err := gh.Exec("org", "list")
+	stdout, stderr, err := ExecContext(ctx, "org", "list")
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to fetch organizations: %s", err))
 		logger.Error(stderr.String())
@@ -25,7 +25,7 @@ func GetOrganizations() ([]string, error) {
 
 	lines := strings.Split(output, "\n")
 
-	authStatus := CheckAuthStatus()
+	authStatus := CheckAuthStatus(ctx)
 	if authStatus.IsAuthenticated && authStatus.Username != "" {
 		username := authStatus.Username
 		logger.Debug(fmt.Sprintf("Adding current user %s to organization options", username))
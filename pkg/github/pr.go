@@ -1,12 +1,12 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/blazity/enterprise-cli/pkg/logging"
-	"github.com/cli/go-gh"
 )
 
 type PROptions struct {
@@ -29,12 +29,12 @@ type PRStatus struct {
 	Passing   bool
 }
 
-func PreparePullRequest(opts PROptions) (string, error) {
+func PreparePullRequest(ctx context.Context, opts PROptions) (string, error) {
 	logger := logging.GetLogger()
 	logger.Debug("Preparing pull request")
 
 	if len(opts.ChangesToCommit) > 0 {
-		if err := CommitChanges(opts.ModifiedDestination, opts.CommitMessage, opts.ChangesToCommit); err != nil {
+		if err := CommitChanges(ctx, opts.ModifiedDestination, opts.CommitMessage, opts.ChangesToCommit); err != nil {
 			return "", err
 		}
 	}
@@ -44,7 +44,7 @@ func PreparePullRequest(opts PROptions) (string, error) {
 		return "", err
 	}
 
-	if err := PushBranch(opts.ModifiedDestination, currentBranch, "origin"); err != nil {
+	if err := PushBranch(ctx, opts.ModifiedDestination, currentBranch, "origin"); err != nil {
 		return "", err
 	}
 
@@ -70,7 +70,7 @@ func PreparePullRequest(opts PROptions) (string, error) {
 		prArgs = append([]string{"--cwd", opts.ModifiedDestination}, prArgs...)
 	}
 
-	stdout, stderr, err := gh.Exec(prArgs...)
+	stdout, stderr, err := ExecContext(ctx, prArgs...)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to create PR: %s", err))
 		logger.Error(stderr.String())
@@ -83,7 +83,7 @@ func PreparePullRequest(opts PROptions) (string, error) {
 	return prURL, nil
 }
 
-func CheckPRStatus(prURL string) (PRStatus, error) {
+func CheckPRStatus(ctx context.Context, prURL string) (PRStatus, error) {
 	logger := logging.GetLogger()
 	status := PRStatus{
 		URL: prURL,
@@ -95,7 +95,7 @@ func CheckPRStatus(prURL string) (PRStatus, error) {
 	}
 
 	viewArgs := []string{"pr", "view", prURL, "--json", "number,state,statusCheckRollup"}
-	stdout, stderr, err := gh.Exec(viewArgs...)
+	stdout, stderr, err := ExecContext(ctx, viewArgs...)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to check PR status: %s", err))
 		logger.Error(stderr.String())
@@ -123,12 +123,12 @@ func CheckPRStatus(prURL string) (PRStatus, error) {
 	return status, nil
 }
 
-func WaitForPRChecks(prURL string, timeout time.Duration) (PRStatus, error) {
+func WaitForPRChecks(ctx context.Context, prURL string, timeout time.Duration) (PRStatus, error) {
 	logger := logging.GetLogger()
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		status, err := CheckPRStatus(prURL)
+		status, err := CheckPRStatus(ctx, prURL)
 		if err != nil {
 			return status, err
 		}
@@ -139,7 +139,11 @@ func WaitForPRChecks(prURL string, timeout time.Duration) (PRStatus, error) {
 		}
 
 		logger.Debug("Waiting for PR checks to complete...")
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ctx.Done():
+			return PRStatus{}, ctx.Err()
+		case <-time.After(30 * time.Second):
+		}
 	}
 
 	return PRStatus{}, fmt.Errorf("timeout waiting for PR checks to complete")
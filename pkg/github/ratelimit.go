@@ -0,0 +1,36 @@
+package github
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimit is the state of the core REST API rate limit of the authenticated user
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// GetRateLimit returns the core rate limit. Checking it does not count against the limit
+func GetRateLimit(ctx context.Context) (RateLimit, error) {
+	var response struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := restRequest(ctx, "GET", "rate_limit", nil, &response); err != nil {
+		return RateLimit{}, err
+	}
+
+	core := response.Resources.Core
+	return RateLimit{
+		Limit:     core.Limit,
+		Remaining: core.Remaining,
+		Reset:     time.Unix(core.Reset, 0),
+	}, nil
+}
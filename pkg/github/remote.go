@@ -1,8 +1,8 @@
 package github
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/blazity/enterprise-cli/pkg/logging"
@@ -14,16 +14,13 @@ func SetRemote(path, name, url string) error {
 	logger := logging.GetLogger()
 	// Check if remote exists by trying to get its URL
 	logger.Debug("Checking for existing git remote", "name", name, "path", path)
-	getCmd := exec.Command("git", "-C", path, "remote", "get-url", name)
-	output, err := getCmd.CombinedOutput()
+	output, err := gitCombinedOutput(context.Background(), path, "remote", "get-url", name)
 	if err != nil {
 		errMsg := string(output)
-		// remote does not exist
-		if strings.Contains(errMsg, fmt.Sprintf("fatal: No such remote '%s'", name)) ||
-			strings.Contains(errMsg, fmt.Sprintf("fatal: no such remote '%s'", name)) {
+		// remote does not exist, reported as "fatal:" or, by newer git, "error:"
+		if strings.Contains(strings.ToLower(errMsg), fmt.Sprintf("no such remote '%s'", strings.ToLower(name))) {
 			logger.Debug("Adding new git remote", "name", name, "url", url)
-			addCmd := exec.Command("git", "-C", path, "remote", "add", name, url)
-			out, addErr := addCmd.CombinedOutput()
+			out, addErr := gitCombinedOutput(context.Background(), path, "remote", "add", name, url)
 			if addErr != nil {
 				logger.Error("Failed to add git remote", "name", name, "url", url, "error", addErr)
 				logger.Error(string(out))
@@ -43,8 +40,7 @@ func SetRemote(path, name, url string) error {
 	// remote exists - update its URL
 	oldURL := strings.TrimSpace(string(output))
 	logger.Debug("Remote exists, updating URL", "name", name, "old-url", oldURL, "new-url", url)
-	setCmd := exec.Command("git", "-C", path, "remote", "set-url", name, url)
-	out, setErr := setCmd.CombinedOutput()
+	out, setErr := gitCombinedOutput(context.Background(), path, "remote", "set-url", name, url)
 	if setErr != nil {
 		logger.Error("Failed to set git remote URL", "name", name, "url", url, "error", setErr)
 		logger.Error(string(out))
@@ -55,3 +51,36 @@ func SetRemote(path, name, url string) error {
 	}
 	return nil
 }
+
+// SetRemoteDefaultBranch makes branch the default branch of the GitHub repository and points
+// the local refs/remotes/<remote>/HEAD at it
+func SetRemoteDefaultBranch(ctx context.Context, path, remote, repo, branch string) error {
+	logger := logging.GetLogger()
+	logger.Debug("Setting default branch", "repo", repo, "branch", branch)
+
+	_, stderr, err := ExecContext(ctx, "repo", "edit", repo, "--default-branch", branch)
+	if err != nil {
+		logger.Error(stderr.String())
+		return fmt.Errorf("failed to set the default branch of %s: %w", repo, err)
+	}
+
+	if out, err := gitCombinedOutput(ctx, path, "remote", "set-head", remote, branch); err != nil {
+		logger.Debug("Could not update the remote HEAD", "remote", remote, "error", err)
+		logger.Debug(string(out))
+	}
+	return nil
+}
+
+// DeleteRepository deletes the GitHub repository, which needs a token with the delete_repo scope
+func DeleteRepository(ctx context.Context, repo string) error {
+	logging.GetLogger().Debug("Deleting repository", "repo", repo)
+
+	_, stderr, err := ExecContext(ctx, "repo", "delete", repo, "--yes")
+	if err != nil {
+		if strings.Contains(stderr.String(), "delete_repo") {
+			return fmt.Errorf("failed to delete %s, grant the delete_repo scope with gh auth refresh -s delete_repo: %w", repo, err)
+		}
+		return fmt.Errorf("failed to delete %s: %w", repo, err)
+	}
+	return nil
+}
@@ -1,17 +1,22 @@
 package github
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/blazity/enterprise-cli/pkg/logging"
-	"github.com/cli/go-gh"
 )
 
 type WorkflowRun struct {
 	ID         string
 	Name       string
+	Title      string
+	Branch     string
+	Event      string
 	Status     string
 	Conclusion string
 	URL        string
@@ -19,7 +24,61 @@ type WorkflowRun struct {
 	UpdatedAt  string
 }
 
-func GetWorkflowRuns(repo string, branch string) ([]WorkflowRun, error) {
+// runFields are the gh run fields decoded into a WorkflowRun
+const runFields = "databaseId,name,displayTitle,headBranch,event,status,conclusion,url,createdAt,updatedAt"
+
+// ghRun is a workflow run as printed by gh run list --json and gh run view --json
+type ghRun struct {
+	DatabaseID   int64  `json:"databaseId"`
+	Name         string `json:"name"`
+	DisplayTitle string `json:"displayTitle"`
+	HeadBranch   string `json:"headBranch"`
+	Event        string `json:"event"`
+	Status       string `json:"status"`
+	Conclusion   string `json:"conclusion"`
+	URL          string `json:"url"`
+	CreatedAt    string `json:"createdAt"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+func (r ghRun) workflowRun() WorkflowRun {
+	return WorkflowRun{
+		ID:         strconv.FormatInt(r.DatabaseID, 10),
+		Name:       r.Name,
+		Title:      r.DisplayTitle,
+		Branch:     r.HeadBranch,
+		Event:      r.Event,
+		Status:     r.Status,
+		Conclusion: r.Conclusion,
+		URL:        r.URL,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
+
+// WorkflowJob is a job of a workflow run with its steps
+type WorkflowJob struct {
+	ID          string
+	Name        string
+	Status      string
+	Conclusion  string
+	URL         string
+	StartedAt   time.Time
+	CompletedAt time.Time
+	Steps       []WorkflowStep
+}
+
+// WorkflowStep is a step of a workflow job
+type WorkflowStep struct {
+	Number      int
+	Name        string
+	Status      string
+	Conclusion  string
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+func GetWorkflowRuns(ctx context.Context, repo string, branch string) ([]WorkflowRun, error) {
 	logger := logging.GetLogger()
 	logger.Debug(fmt.Sprintf("Getting workflow runs for %s branch %s", repo, branch))
 
@@ -29,64 +88,23 @@ func GetWorkflowRuns(repo string, branch string) ([]WorkflowRun, error) {
 		args = append(args, "--branch", branch)
 	}
 
-	args = append(args, "--json", "databaseId,name,status,conclusion,url,createdAt,updatedAt")
+	args = append(args, "--json", runFields)
 
-	stdout, stderr, err := gh.Exec(args...)
+	stdout, stderr, err := ExecContext(ctx, args...)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to get workflow runs: %s", err))
 		logger.Error(stderr.String())
 		return nil, err
 	}
 
-	output := stdout.String()
-
-	var runs []WorkflowRun
-	lines := strings.Split(output, "}")
-	for _, line := range lines {
-		if !strings.Contains(line, "databaseId") {
-			continue
-		}
-
-		run := WorkflowRun{}
-
-		if idParts := strings.Split(line, "\"databaseId\": "); len(idParts) > 1 {
-			idStr := strings.Split(idParts[1], ",")[0]
-			run.ID = strings.TrimSpace(idStr)
-		}
-
-		if nameParts := strings.Split(line, "\"name\": \""); len(nameParts) > 1 {
-			name := strings.Split(nameParts[1], "\"")[0]
-			run.Name = name
-		}
-
-		if statusParts := strings.Split(line, "\"status\": \""); len(statusParts) > 1 {
-			status := strings.Split(statusParts[1], "\"")[0]
-			run.Status = status
-		}
-
-		if conclParts := strings.Split(line, "\"conclusion\": \""); len(conclParts) > 1 {
-			concl := strings.Split(conclParts[1], "\"")[0]
-			run.Conclusion = concl
-		}
-
-		if urlParts := strings.Split(line, "\"url\": \""); len(urlParts) > 1 {
-			url := strings.Split(urlParts[1], "\"")[0]
-			run.URL = url
-		}
-
-		if dateParts := strings.Split(line, "\"createdAt\": \""); len(dateParts) > 1 {
-			date := strings.Split(dateParts[1], "\"")[0]
-			run.CreatedAt = date
-		}
-
-		if dateParts := strings.Split(line, "\"updatedAt\": \""); len(dateParts) > 1 {
-			date := strings.Split(dateParts[1], "\"")[0]
-			run.UpdatedAt = date
-		}
+	var decoded []ghRun
+	if err := json.Unmarshal(stdout.Bytes(), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow runs: %w", err)
+	}
 
-		if run.ID != "" {
-			runs = append(runs, run)
-		}
+	runs := make([]WorkflowRun, 0, len(decoded))
+	for _, run := range decoded {
+		runs = append(runs, run.workflowRun())
 	}
 
 	logger.Debug(fmt.Sprintf("Found %d workflow runs", len(runs)))
@@ -94,9 +112,9 @@ func GetWorkflowRuns(repo string, branch string) ([]WorkflowRun, error) {
 	return runs, nil
 }
 
-func GetLatestWorkflowRun(repo string, branch string) (*WorkflowRun, error) {
+func GetLatestWorkflowRun(ctx context.Context, repo string, branch string) (*WorkflowRun, error) {
 	logger := logging.GetLogger()
-	runs, err := GetWorkflowRuns(repo, branch)
+	runs, err := GetWorkflowRuns(ctx, repo, branch)
 	if err != nil {
 		return nil, err
 	}
@@ -114,40 +132,43 @@ func GetLatestWorkflowRun(repo string, branch string) (*WorkflowRun, error) {
 	return &latestRun, nil
 }
 
-func WaitForWorkflowRun(repo string, branch string, timeout time.Duration) (*WorkflowRun, error) {
+func WaitForWorkflowRun(ctx context.Context, repo string, branch string, timeout time.Duration) (*WorkflowRun, error) {
 	logger := logging.GetLogger()
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		run, err := GetLatestWorkflowRun(repo, branch)
+		run, err := GetLatestWorkflowRun(ctx, repo, branch)
 		if err != nil {
 			return nil, err
 		}
 
+		interval := 30 * time.Second
 		if run == nil {
 			logger.Debug("No workflow runs found yet, waiting...")
-			time.Sleep(10 * time.Second)
-			continue
-		}
-
-		if run.Status == "completed" {
+			interval = 10 * time.Second
+		} else if run.Status == "completed" {
 			logger.Info(fmt.Sprintf("Workflow run completed with conclusion: %s", run.Conclusion))
 			return run, nil
+		} else {
+			logger.Debug(fmt.Sprintf("Workflow run status: %s, waiting...", run.Status))
 		}
 
-		logger.Debug(fmt.Sprintf("Workflow run status: %s, waiting...", run.Status))
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
 	}
 
 	return nil, fmt.Errorf("timeout waiting for workflow run to complete")
 }
 
-func CancelWorkflowRun(runID string, repo string) error {
+func CancelWorkflowRun(ctx context.Context, runID string, repo string) error {
 	logger := logging.GetLogger()
 	logger.Debug(fmt.Sprintf("Cancelling workflow run %s", runID))
 
 	args := []string{"run", "cancel", runID, "--repo", repo}
-	_, stderr, err := gh.Exec(args...)
+	_, stderr, err := ExecContext(ctx, args...)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to cancel workflow run: %s", err))
 		logger.Error(stderr.String())
@@ -159,12 +180,12 @@ func CancelWorkflowRun(runID string, repo string) error {
 	return nil
 }
 
-func GetWorkflowRunLogs(runID string, repo string) (string, error) {
+func GetWorkflowRunLogs(ctx context.Context, runID string, repo string) (string, error) {
 	logger := logging.GetLogger()
 	logger.Debug(fmt.Sprintf("Getting logs for workflow run %s", runID))
 
 	args := []string{"run", "view", runID, "--repo", repo, "--log"}
-	stdout, stderr, err := gh.Exec(args...)
+	stdout, stderr, err := ExecContext(ctx, args...)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to get workflow logs: %s", err))
 		logger.Error(stderr.String())
@@ -195,46 +216,200 @@ func ExtractRepoFromURL(url string) (string, error) {
 	return fmt.Sprintf("%s/%s", owner, repo), nil
 }
 
-func GetWorkflowRunByID(runID string, repo string) (*WorkflowRun, error) {
+func GetWorkflowRunByID(ctx context.Context, runID string, repo string) (*WorkflowRun, error) {
 	logger := logging.GetLogger()
 	logger.Debug(fmt.Sprintf("Getting workflow run %s by ID", runID))
 
-	args := []string{"run", "view", runID, "--repo", repo, "--json", "databaseId,name,status,conclusion,url,createdAt,updatedAt"}
-	stdout, stderr, err := gh.Exec(args...)
+	args := []string{"run", "view", runID, "--repo", repo, "--json", runFields}
+	stdout, stderr, err := ExecContext(ctx, args...)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to get workflow run: %s", err))
 		logger.Error(stderr.String())
 		return nil, err
 	}
 
-	output := stdout.String()
+	var decoded ghRun
+	if err := json.Unmarshal(stdout.Bytes(), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow run: %w", err)
+	}
+
+	run := decoded.workflowRun()
+	return &run, nil
+}
 
-	run := WorkflowRun{}
+// GetWorkflowRunJobs returns the jobs of a workflow run with their steps
+func GetWorkflowRunJobs(ctx context.Context, runID string, repo string) ([]WorkflowJob, error) {
+	logger := logging.GetLogger()
+	logger.Debug(fmt.Sprintf("Getting jobs of workflow run %s", runID))
 
-	if idParts := strings.Split(output, "\"databaseId\": "); len(idParts) > 1 {
-		idStr := strings.Split(idParts[1], ",")[0]
-		run.ID = strings.TrimSpace(idStr)
+	args := []string{"run", "view", runID, "--repo", repo, "--json", "jobs"}
+	stdout, stderr, err := ExecContext(ctx, args...)
+	if err != nil {
+		logger.Debug(stderr.String())
+		return nil, fmt.Errorf("failed to get jobs of workflow run %s: %w", runID, err)
 	}
 
-	if nameParts := strings.Split(output, "\"name\": \""); len(nameParts) > 1 {
-		name := strings.Split(nameParts[1], "\"")[0]
-		run.Name = name
+	var decoded struct {
+		Jobs []struct {
+			DatabaseID  int64     `json:"databaseId"`
+			Name        string    `json:"name"`
+			Status      string    `json:"status"`
+			Conclusion  string    `json:"conclusion"`
+			URL         string    `json:"url"`
+			StartedAt   time.Time `json:"startedAt"`
+			CompletedAt time.Time `json:"completedAt"`
+			Steps       []struct {
+				Number      int       `json:"number"`
+				Name        string    `json:"name"`
+				Status      string    `json:"status"`
+				Conclusion  string    `json:"conclusion"`
+				StartedAt   time.Time `json:"startedAt"`
+				CompletedAt time.Time `json:"completedAt"`
+			} `json:"steps"`
+		} `json:"jobs"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow jobs: %w", err)
 	}
 
-	if statusParts := strings.Split(output, "\"status\": \""); len(statusParts) > 1 {
-		status := strings.Split(statusParts[1], "\"")[0]
-		run.Status = status
+	jobs := make([]WorkflowJob, 0, len(decoded.Jobs))
+	for _, j := range decoded.Jobs {
+		job := WorkflowJob{
+			ID:          strconv.FormatInt(j.DatabaseID, 10),
+			Name:        j.Name,
+			Status:      j.Status,
+			Conclusion:  j.Conclusion,
+			URL:         j.URL,
+			StartedAt:   j.StartedAt,
+			CompletedAt: j.CompletedAt,
+		}
+		for _, st := range j.Steps {
+			job.Steps = append(job.Steps, WorkflowStep{
+				Number:      st.Number,
+				Name:        st.Name,
+				Status:      st.Status,
+				Conclusion:  st.Conclusion,
+				StartedAt:   st.StartedAt,
+				CompletedAt: st.CompletedAt,
+			})
+		}
+		jobs = append(jobs, job)
 	}
 
-	if conclParts := strings.Split(output, "\"conclusion\": \""); len(conclParts) > 1 {
-		concl := strings.Split(conclParts[1], "\"")[0]
-		run.Conclusion = concl
+	return jobs, nil
+}
+
+// RerunFailedJobs re-runs the failed jobs of a completed workflow run and the jobs depending on them
+func RerunFailedJobs(ctx context.Context, runID string, repo string) error {
+	logging.GetLogger().Debug(fmt.Sprintf("Re-running failed jobs of workflow run %s", runID))
+
+	_, stderr, err := ExecContext(ctx, "run", "rerun", runID, "--failed", "--repo", repo)
+	if err != nil {
+		return fmt.Errorf("failed to re-run workflow run %s: %s", runID, strings.TrimSpace(stderr.String()))
 	}
+	return nil
+}
+
+// GetFailedWorkflowRunLogs returns the logs of the failed steps of a workflow run
+func GetFailedWorkflowRunLogs(ctx context.Context, runID string, repo string) (string, error) {
+	logging.GetLogger().Debug(fmt.Sprintf("Getting failed step logs for workflow run %s", runID))
 
-	if urlParts := strings.Split(output, "\"url\": \""); len(urlParts) > 1 {
-		url := strings.Split(urlParts[1], "\"")[0]
-		run.URL = url
+	stdout, stderr, err := ExecContext(ctx, "run", "view", runID, "--repo", repo, "--log-failed")
+	if err != nil {
+		return "", fmt.Errorf("failed to get logs of workflow run %s: %s", runID, strings.TrimSpace(stderr.String()))
 	}
+	return stdout.String(), nil
+}
 
-	return &run, nil
+// OpenWorkflowRun opens a workflow run in the browser
+func OpenWorkflowRun(ctx context.Context, runID string, repo string) error {
+	_, stderr, err := ExecContext(ctx, "run", "view", runID, "--repo", repo, "--web")
+	if err != nil {
+		return fmt.Errorf("failed to open workflow run %s: %s", runID, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// DispatchWorkflow triggers a workflow_dispatch event for workflow on ref
+func DispatchWorkflow(ctx context.Context, repo string, workflow string, ref string) error {
+	logger := logging.GetLogger()
+	logger.Debug(fmt.Sprintf("Dispatching workflow %s on %s in %s", workflow, ref, repo))
+
+	args := []string{"workflow", "run", workflow, "--repo", repo, "--ref", ref}
+	_, stderr, err := ExecContext(ctx, args...)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to dispatch workflow: %s", err))
+		logger.Error(stderr.String())
+		return err
+	}
+
+	return nil
+}
+
+// WaitForDispatchedRun waits for the run of workflow dispatched at or after since to complete.
+// The run is looked up by creation time, since dispatching does not return its ID.
+func WaitForDispatchedRun(ctx context.Context, repo string, workflow string, since time.Time, timeout time.Duration) (*WorkflowRun, error) {
+	logger := logging.GetLogger()
+	deadline := time.Now().Add(timeout)
+	runID := ""
+
+	for time.Now().Before(deadline) {
+		if runID == "" {
+			id, err := findDispatchedRunID(ctx, repo, workflow, since)
+			if err != nil {
+				return nil, err
+			}
+			runID = id
+			if runID == "" {
+				logger.Debug("Dispatched workflow run not found yet, waiting...")
+			}
+		}
+
+		if runID != "" {
+			run, err := GetWorkflowRunByID(ctx, runID, repo)
+			if err != nil {
+				return nil, err
+			}
+			if run.Status == "completed" {
+				logger.Info(fmt.Sprintf("Workflow run completed with conclusion: %s", run.Conclusion))
+				return run, nil
+			}
+			logger.Debug(fmt.Sprintf("Workflow run status: %s, waiting...", run.Status))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+
+	return nil, fmt.Errorf("timeout waiting for workflow run to complete")
+}
+
+func findDispatchedRunID(ctx context.Context, repo string, workflow string, since time.Time) (string, error) {
+	args := []string{"run", "list", "--repo", repo, "--workflow", workflow, "--event", "workflow_dispatch", "--limit", "5", "--json", "databaseId,createdAt"}
+	stdout, stderr, err := ExecContext(ctx, args...)
+	if err != nil {
+		logging.GetLogger().Error(fmt.Sprintf("Failed to list workflow runs: %s", err))
+		logging.GetLogger().Error(stderr.String())
+		return "", err
+	}
+
+	var runs []struct {
+		DatabaseID int64     `json:"databaseId"`
+		CreatedAt  time.Time `json:"createdAt"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &runs); err != nil {
+		return "", fmt.Errorf("failed to decode workflow runs: %w", err)
+	}
+
+	// Allow for clock skew between this machine and GitHub
+	threshold := since.Add(-time.Minute)
+	for _, run := range runs {
+		if run.CreatedAt.After(threshold) {
+			return strconv.FormatInt(run.DatabaseID, 10), nil
+		}
+	}
+	return "", nil
 }
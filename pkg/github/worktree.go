@@ -0,0 +1,146 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/logging"
+)
+
+// WorkingTreeStatus lists the uncommitted changes of a working tree
+type WorkingTreeStatus struct {
+	Staged    []string
+	Unstaged  []string
+	Untracked []string
+}
+
+// IsClean reports whether the working tree has no uncommitted changes
+func (s *WorkingTreeStatus) IsClean() bool {
+	return len(s.Staged) == 0 && len(s.Unstaged) == 0 && len(s.Untracked) == 0
+}
+
+// Paths returns every changed path once
+func (s *WorkingTreeStatus) Paths() []string {
+	seen := map[string]bool{}
+	var paths []string
+	for _, list := range [][]string{s.Staged, s.Unstaged, s.Untracked} {
+		for _, path := range list {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// GetWorkingTreeStatus returns the uncommitted changes of the working tree at path, ignored files excluded
+func GetWorkingTreeStatus(path string) (*WorkingTreeStatus, error) {
+	output, err := gitOutput(context.Background(), path, "status", "--porcelain=v1", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working tree status: %w", err)
+	}
+
+	status := &WorkingTreeStatus{}
+	records := strings.Split(string(output), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if len(record) < 4 {
+			continue
+		}
+		x, y, file := record[0], record[1], record[3:]
+		if x == 'R' || x == 'C' {
+			// Renames and copies are followed by their source path
+			i++
+		}
+
+		switch {
+		case x == '?' && y == '?':
+			status.Untracked = append(status.Untracked, file)
+		default:
+			if x != ' ' {
+				status.Staged = append(status.Staged, file)
+			}
+			if y != ' ' {
+				status.Unstaged = append(status.Unstaged, file)
+			}
+		}
+	}
+	return status, nil
+}
+
+// Stash saves all uncommitted changes, untracked files included, and returns the stash commit
+func Stash(path string, message string) (string, error) {
+	logger := logging.GetLogger()
+
+	if output, err := gitCombinedOutput(context.Background(), path, "stash", "push", "--include-untracked", "-m", message); err != nil {
+		logger.Error(string(output))
+		return "", fmt.Errorf("failed to stash changes: %w", err)
+	}
+
+	output, err := gitOutput(context.Background(), path, "rev-parse", "stash@{0}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the stash: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// StashPop restores the most recent stash, which must be the stash commit returned by Stash
+func StashPop(path string, stash string) error {
+	output, err := gitOutput(context.Background(), path, "rev-parse", "stash@{0}")
+	if err != nil || strings.TrimSpace(string(output)) != stash {
+		return fmt.Errorf("stash %s is no longer the latest stash, restore it with git stash apply %s", stash, stash)
+	}
+
+	if output, err := gitCombinedOutput(context.Background(), path, "stash", "pop"); err != nil {
+		logging.GetLogger().Error(string(output))
+		return fmt.Errorf("failed to restore stashed changes: %w", err)
+	}
+	return nil
+}
+
+// AddWorktree checks out a new branch created from base in a separate worktree at dir
+func AddWorktree(path string, dir string, branch string, base string) error {
+	logger := logging.GetLogger()
+	logger.Debug(fmt.Sprintf("Creating worktree %s on new branch %s from %s", dir, branch, base))
+
+	if output, err := gitCombinedOutput(context.Background(), path, "worktree", "add", "-b", branch, dir, base); err != nil {
+		logger.Error(string(output))
+		return fmt.Errorf("failed to create worktree: %w", err)
+	}
+	return nil
+}
+
+// RemoveWorktree deletes the worktree at dir, discarding anything left in it
+func RemoveWorktree(path string, dir string) error {
+	if output, err := gitCombinedOutput(context.Background(), path, "worktree", "remove", "--force", dir); err != nil {
+		logging.GetLogger().Debug(string(output))
+		return fmt.Errorf("failed to remove worktree %s: %w", dir, err)
+	}
+	return nil
+}
+
+// FastForward advances the branch checked out at path to target, refusing anything but a fast-forward
+func FastForward(path string, target string) error {
+	if output, err := gitCombinedOutput(context.Background(), path, "merge", "--ff-only", target); err != nil {
+		logging.GetLogger().Debug(string(output))
+		return fmt.Errorf("failed to fast-forward to %s: %w", target, err)
+	}
+	return nil
+}
+
+// GitCommonDir returns the absolute git directory shared by all worktrees of the repository at path
+func GitCommonDir(path string) (string, error) {
+	output, err := gitOutput(context.Background(), path, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the git directory: %w", err)
+	}
+
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(path, dir)
+	}
+	return filepath.Abs(dir)
+}
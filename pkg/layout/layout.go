@@ -0,0 +1,154 @@
+package layout
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Template paths, the application and infrastructure directories the upstream template is written for
+const (
+	TemplateAppDir   = "frontend"
+	TemplateInfraDir = "terraform"
+)
+
+// Workspace selects the root package.json set up around the application.
+// WorkspaceNpm declares the "workspaces" field, which npm, yarn and bun all read.
+type Workspace string
+
+const (
+	WorkspaceNone  Workspace = "none"
+	WorkspaceNpm   Workspace = "npm"
+	WorkspacePnpm  Workspace = "pnpm"
+	WorkspaceTurbo Workspace = "turbo"
+)
+
+// workspaceAliases maps former workspace kinds to the kind producing the same files
+var workspaceAliases = map[Workspace]Workspace{
+	"yarn": WorkspaceNpm,
+}
+
+// Workspaces lists the valid workspace kinds
+var Workspaces = []Workspace{WorkspaceNone, WorkspaceNpm, WorkspacePnpm, WorkspaceTurbo}
+
+// baseRootEntries always stay at the repository root
+var baseRootEntries = []string{".github", "README.md", "LICENSE", ".gitignore", ".git"}
+
+// Layout describes where the application and the infrastructure live in the prepared repository
+type Layout struct {
+	AppDir    string
+	InfraDir  string
+	Workspace Workspace
+	// KeepAtRoot lists additional top-level entries that are not moved into AppDir
+	KeepAtRoot []string
+}
+
+// Default returns the layout the template is written for
+func Default() Layout {
+	return Layout{
+		AppDir:    TemplateAppDir,
+		InfraDir:  TemplateInfraDir,
+		Workspace: WorkspaceNone,
+	}
+}
+
+// Normalize fills in defaults, cleans the directories and validates the layout
+func (l *Layout) Normalize() error {
+	if l.AppDir == "" {
+		l.AppDir = TemplateAppDir
+	}
+	if l.InfraDir == "" {
+		l.InfraDir = TemplateInfraDir
+	}
+	if l.Workspace == "" {
+		l.Workspace = WorkspaceNone
+	}
+	if alias, ok := workspaceAliases[l.Workspace]; ok {
+		l.Workspace = alias
+	}
+
+	for _, dir := range []*string{&l.AppDir, &l.InfraDir} {
+		cleaned := path.Clean(filepath.ToSlash(*dir))
+		if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return fmt.Errorf("'%s' must be a subdirectory of the repository", *dir)
+		}
+		if top := topLevel(cleaned); top == ".git" || top == ".github" {
+			return fmt.Errorf("'%s' cannot be placed in %s", *dir, top)
+		}
+		*dir = cleaned
+	}
+
+	if l.AppDir == l.InfraDir || strings.HasPrefix(l.AppDir+"/", l.InfraDir+"/") || strings.HasPrefix(l.InfraDir+"/", l.AppDir+"/") {
+		return fmt.Errorf("application directory '%s' and infrastructure directory '%s' must not overlap", l.AppDir, l.InfraDir)
+	}
+
+	valid := false
+	for _, workspace := range Workspaces {
+		if l.Workspace == workspace {
+			valid = true
+		}
+	}
+	if !valid {
+		names := make([]string, 0, len(Workspaces))
+		for _, workspace := range Workspaces {
+			names = append(names, string(workspace))
+		}
+		return fmt.Errorf("unknown workspace '%s', expected one of: %s", l.Workspace, strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// RootEntries returns the top-level entries that stay at the repository root, plus extra
+func (l Layout) RootEntries(extra ...string) []string {
+	entries := append([]string{}, baseRootEntries...)
+	entries = append(entries, topLevel(l.InfraDir))
+	entries = append(entries, l.KeepAtRoot...)
+	entries = append(entries, extra...)
+	return entries
+}
+
+// PathRewrites maps the template directories to their configured locations
+func (l Layout) PathRewrites() map[string]string {
+	rewrites := map[string]string{}
+	if l.AppDir != TemplateAppDir {
+		rewrites[TemplateAppDir] = l.AppDir
+	}
+	if l.InfraDir != TemplateInfraDir {
+		rewrites[TemplateInfraDir] = l.InfraDir
+	}
+	return rewrites
+}
+
+// RewritePath replaces the leading directory of p with its rewrite, keeping a "./" or "!" prefix.
+// Paths that do not start with one of the rewritten directories are returned unchanged.
+func RewritePath(p string, rewrites map[string]string) string {
+	prefix := ""
+	rest := p
+	if strings.HasPrefix(rest, "!") {
+		prefix, rest = "!", rest[1:]
+	}
+	if strings.HasPrefix(rest, "./") {
+		prefix, rest = prefix+"./", rest[2:]
+	}
+
+	from := make([]string, 0, len(rewrites))
+	for dir := range rewrites {
+		from = append(from, dir)
+	}
+	// Longest first, so nested directories win over their parents
+	sort.Slice(from, func(i, j int) bool { return len(from[i]) > len(from[j]) })
+
+	for _, dir := range from {
+		if rest == dir || strings.HasPrefix(rest, dir+"/") {
+			return prefix + rewrites[dir] + rest[len(dir):]
+		}
+	}
+	return p
+}
+
+func topLevel(p string) string {
+	return strings.SplitN(p, "/", 2)[0]
+}
@@ -0,0 +1,119 @@
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// rootPackageJson is the workspace root package.json, its fields in the order npm writes them
+type rootPackageJson struct {
+	Name            string            `json:"name"`
+	Private         bool              `json:"private"`
+	Workspaces      []string          `json:"workspaces,omitempty"`
+	Scripts         map[string]string `json:"scripts,omitempty"`
+	DevDependencies map[string]string `json:"devDependencies,omitempty"`
+}
+
+// WorkspaceFiles returns the root files WriteWorkspace creates for the workspace kind
+func (l Layout) WorkspaceFiles(packageManager string) []string {
+	switch l.Workspace {
+	case WorkspaceNone:
+		return nil
+	case WorkspaceTurbo:
+		if packageManager == "pnpm" {
+			return []string{"package.json", "pnpm-workspace.yaml", "turbo.json"}
+		}
+		return []string{"package.json", "turbo.json"}
+	case WorkspacePnpm:
+		return []string{"package.json", "pnpm-workspace.yaml"}
+	}
+	return []string{"package.json"}
+}
+
+// WriteWorkspace creates the root workspace files around the application in root.
+// Turborepo workspaces are declared the way packageManager expects them.
+// Existing files are never overwritten. It returns the paths it wrote.
+func WriteWorkspace(root string, l Layout, name string, packageManager string) ([]string, error) {
+	if l.Workspace == WorkspaceNone {
+		return nil, nil
+	}
+
+	for _, file := range l.WorkspaceFiles(packageManager) {
+		if _, err := os.Stat(filepath.Join(root, file)); err == nil {
+			return nil, fmt.Errorf("cannot set up the workspace, %s already exists at the repository root", file)
+		}
+	}
+
+	usePnpm := l.Workspace == WorkspacePnpm || (l.Workspace == WorkspaceTurbo && packageManager == "pnpm")
+
+	pkg := rootPackageJson{
+		Name:    name,
+		Private: true,
+	}
+	if !usePnpm {
+		pkg.Workspaces = []string{l.AppDir}
+	}
+	if l.Workspace == WorkspaceTurbo {
+		pkg.Scripts = map[string]string{
+			"build": "turbo run build",
+			"dev":   "turbo run dev",
+			"lint":  "turbo run lint",
+		}
+		pkg.DevDependencies = map[string]string{
+			"turbo": "^2.0.0",
+		}
+	}
+
+	written := []string{}
+	write := func(file string, content []byte) error {
+		target := filepath.Join(root, file)
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", target, err)
+		}
+		written = append(written, target)
+		return nil
+	}
+
+	data, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := write("package.json", append(data, '\n')); err != nil {
+		return written, err
+	}
+
+	if usePnpm {
+		content := fmt.Sprintf("packages:\n  - %q\n", l.AppDir)
+		if err := write("pnpm-workspace.yaml", []byte(content)); err != nil {
+			return written, err
+		}
+	}
+
+	if l.Workspace == WorkspaceTurbo {
+		turbo := map[string]interface{}{
+			"$schema": "https://turbo.build/schema.json",
+			"tasks": map[string]interface{}{
+				"build": map[string]interface{}{
+					"dependsOn": []string{"^build"},
+					"outputs":   []string{".next/**", "!.next/cache/**"},
+				},
+				"dev": map[string]interface{}{
+					"cache":      false,
+					"persistent": true,
+				},
+				"lint": map[string]interface{}{},
+			},
+		}
+		data, err := json.MarshalIndent(turbo, "", "  ")
+		if err != nil {
+			return written, err
+		}
+		if err := write("turbo.json", append(data, '\n')); err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
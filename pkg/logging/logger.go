@@ -1,8 +1,15 @@
 package logging
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
@@ -12,13 +19,54 @@ type Logger interface {
 	Warning(message string, keyvals ...interface{})
 	Error(message string, keyvals ...interface{})
 	Debug(message interface{}, keyvals ...interface{})
+	// Event logs a machine-readable event, such as the start or end of a step. It is shown on the console
+	// only in verbose mode or with the JSON format, and always written to the log file
+	Event(message string, keyvals ...interface{})
 	IsVerbose() bool
 	SetVerbose(verbose bool)
+	// SetQuiet limits the console to warnings and errors, for example while a progress view shows what
+	// happens. The log file is not affected
+	SetQuiet(quiet bool)
+	SetOutput(w io.Writer)
+	SetFormat(format Format)
+	SetLogFile(path string) error
+	// Close flushes and closes the log file, if one is set. Later lines only go to the console
+	Close() error
+	// RunID returns the ID tying together the lines of this invocation. It is attached to every line of
+	// the log file and to JSON or verbose console lines, plain text console lines leave it out
+	RunID() string
+	// Warnings returns the warnings logged so far, for the structured result of a command
+	Warnings() []string
+}
+
+// Format is the encoding of console log lines
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates a --log-format value
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatText, FormatJSON:
+		return Format(s), nil
+	}
+	return "", fmt.Errorf("invalid log format %q, expected text or json", s)
 }
 
 type logger struct {
 	verbose bool
+	format  Format
+	runID   string
 	log     *log.Logger
+	// mu guards the log file and the warnings
+	mu sync.Mutex
+	// file receives every line at debug level, when a log file is set, and writes to logFile
+	file     *log.Logger
+	logFile  *os.File
+	warnings []string
 }
 
 var (
@@ -57,28 +105,80 @@ func newLogger(verbose bool) Logger {
 
 	return &logger{
 		verbose: verbose,
+		format:  FormatText,
+		runID:   newRunID(),
 		log:     l,
 	}
 }
 
+// newRunID returns a short random ID tying together the log lines of one invocation
+func newRunID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
 func (l *logger) Info(message string, keyvals ...interface{}) {
 	l.log.Helper()
-	l.log.Info(message, keyvals...)
+	message, keyvals = Redact(message), redactKeyvals(keyvals)
+	l.log.Info(message, l.consoleKeyvals(keyvals)...)
+	l.toFile(log.InfoLevel, message, keyvals)
 }
 
 func (l *logger) Warning(message string, keyvals ...interface{}) {
 	l.log.Helper()
-	l.log.Warn(message, keyvals...)
+	message, keyvals = Redact(message), redactKeyvals(keyvals)
+	l.log.Warn(message, l.consoleKeyvals(keyvals)...)
+	l.toFile(log.WarnLevel, message, keyvals)
+
+	l.mu.Lock()
+	l.warnings = append(l.warnings, formatLine(message, keyvals))
+	l.mu.Unlock()
 }
 
 func (l *logger) Error(message string, keyvals ...interface{}) {
 	l.log.Helper()
-	l.log.Error(message, keyvals...)
+	message, keyvals = Redact(message), redactKeyvals(keyvals)
+	l.log.Error(message, l.consoleKeyvals(keyvals)...)
+	l.toFile(log.ErrorLevel, message, keyvals)
 }
 
 func (l *logger) Debug(message interface{}, keyvals ...interface{}) {
 	l.log.Helper()
-	l.log.Debug(message, keyvals...)
+	message, keyvals = redactValue(message), redactKeyvals(keyvals)
+	l.log.Debug(message, l.consoleKeyvals(keyvals)...)
+	l.toFile(log.DebugLevel, message, keyvals)
+}
+
+func (l *logger) Event(message string, keyvals ...interface{}) {
+	l.log.Helper()
+	message, keyvals = Redact(message), redactKeyvals(keyvals)
+	if l.format == FormatJSON {
+		l.log.Info(message, l.consoleKeyvals(keyvals)...)
+	} else {
+		l.log.Debug(message, l.consoleKeyvals(keyvals)...)
+	}
+	l.toFile(log.InfoLevel, message, keyvals)
+}
+
+// consoleKeyvals adds the run ID to console lines meant for machines or for debugging. Plain text lines
+// are read by people and leave it out, it can be found in the log file or the structured result instead
+func (l *logger) consoleKeyvals(keyvals []interface{}) []interface{} {
+	if l.format != FormatJSON && !l.verbose {
+		return keyvals
+	}
+	return append([]interface{}{"run_id", l.runID}, keyvals...)
+}
+
+func (l *logger) toFile(level log.Level, message interface{}, keyvals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	l.file.Log(level, message, append([]interface{}{"run_id", l.runID}, keyvals...)...)
 }
 
 func (l *logger) IsVerbose() bool {
@@ -95,3 +195,105 @@ func (l *logger) SetVerbose(verbose bool) {
 		l.log.SetReportCaller(false)
 	}
 }
+
+func (l *logger) SetQuiet(quiet bool) {
+	switch {
+	case quiet:
+		l.log.SetLevel(log.WarnLevel)
+	case l.verbose:
+		l.log.SetLevel(log.DebugLevel)
+	default:
+		l.log.SetLevel(log.InfoLevel)
+	}
+}
+
+// SetOutput redirects log lines to w, for example away from a full screen view
+func (l *logger) SetOutput(w io.Writer) {
+	l.log.SetOutput(w)
+}
+
+// SetFormat switches the console between styled text and one JSON object per line
+func (l *logger) SetFormat(format Format) {
+	l.format = format
+	if format == FormatJSON {
+		l.log.SetFormatter(log.JSONFormatter)
+		l.log.SetReportTimestamp(true)
+		l.log.SetTimeFormat(time.RFC3339)
+	} else {
+		l.log.SetFormatter(log.TextFormatter)
+	}
+}
+
+// SetLogFile appends every log line to path at debug level, whatever the console level is.
+// The file uses the console format
+func (l *logger) SetLogFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.closeFile(); err != nil {
+		f.Close()
+		return err
+	}
+
+	l.logFile = f
+	l.file = log.NewWithOptions(f, log.Options{
+		ReportTimestamp: true,
+		TimeFormat:      time.RFC3339,
+		Level:           log.DebugLevel,
+	})
+	if l.format == FormatJSON {
+		l.file.SetFormatter(log.JSONFormatter)
+	}
+	return nil
+}
+
+// Close syncs and closes the log file, so its lines are on disk when the command returns
+func (l *logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closeFile()
+}
+
+// closeFile closes the log file, with mu held
+func (l *logger) closeFile() error {
+	if l.logFile == nil {
+		return nil
+	}
+	f := l.logFile
+	l.file, l.logFile = nil, nil
+
+	syncErr := f.Sync()
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	if syncErr != nil {
+		return fmt.Errorf("failed to flush log file: %w", syncErr)
+	}
+	return nil
+}
+
+// Warnings returns the messages of the warnings logged so far, with their keyvals
+func (l *logger) Warnings() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.warnings...)
+}
+
+// formatLine renders message and keyvals on one line, like the text format without styles
+func formatLine(message string, keyvals []interface{}) string {
+	var b strings.Builder
+	b.WriteString(message)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+	}
+	return b.String()
+}
+
+// RunID returns the ID attached to the log lines of this invocation
+func (l *logger) RunID() string {
+	return l.runID
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces sensitive values in log lines
+const Redacted = "[REDACTED]"
+
+// minSecretLength keeps short values, which would mask unrelated words, from being registered
+const minSecretLength = 6
+
+var (
+	secretsMu sync.RWMutex
+	// registered holds the registered values, longest first so a value containing another is masked whole
+	registered []string
+
+	// secretPatterns catch credentials that were never registered, such as ones echoed by a failed command
+	secretPatterns = []*regexp.Regexp{
+		// AWS access key IDs
+		regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA|AGPA|AIDA|AIPA|ANPA|ANVA|AROA|APKA|ASCA)[A-Z0-9]{16}\b`),
+		// AWS secret access keys, recognised by the name they are assigned to
+		regexp.MustCompile(`(?i)(aws_?secret_?access_?key["']?\s*[:=]\s*["']?)[A-Za-z0-9/+=]{40}`),
+		// GitHub personal access, OAuth, user-to-server, server-to-server and refresh tokens
+		regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+		// GitHub fine-grained personal access tokens
+		regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),
+	}
+)
+
+// RegisterSecret masks value in every log line written from now on, on the console and in the log file
+func RegisterSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	for _, existing := range registered {
+		if existing == value {
+			return
+		}
+	}
+	registered = append(registered, value)
+	sort.SliceStable(registered, func(i, j int) bool {
+		return len(registered[i]) > len(registered[j])
+	})
+}
+
+// Redact returns s with the registered secrets and anything looking like a credential masked
+func Redact(s string) string {
+	secretsMu.RLock()
+	for _, value := range registered {
+		s = strings.ReplaceAll(s, value, Redacted)
+	}
+	secretsMu.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		if pattern.NumSubexp() > 0 {
+			s = pattern.ReplaceAllString(s, "${1}"+Redacted)
+		} else {
+			s = pattern.ReplaceAllString(s, Redacted)
+		}
+	}
+	return s
+}
+
+// redactValue masks the secrets in a logged message or keyval. Values whose text holds no secret are
+// returned unchanged, so they keep their formatting
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return Redact(v)
+	case []byte:
+		return Redact(string(v))
+	case bool, int, int64, float64:
+		return v
+	}
+
+	s := fmt.Sprint(v)
+	if redacted := Redact(s); redacted != s {
+		return redacted
+	}
+	return v
+}
+
+func redactKeyvals(keyvals []interface{}) []interface{} {
+	redacted := make([]interface{}, len(keyvals))
+	for i, v := range keyvals {
+		redacted[i] = redactValue(v)
+	}
+	return redacted
+}
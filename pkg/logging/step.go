@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// StepObserver is notified when steps start and finish, for example to show their progress
+type StepObserver interface {
+	StepStarted(name string)
+	StepFinished(name string, status string, duration time.Duration, err error)
+}
+
+var (
+	observerMu sync.RWMutex
+	observer   StepObserver
+)
+
+// SetStepObserver notifies o of every step from now on, or stops notifying when o is nil
+func SetStepObserver(o StepObserver) {
+	observerMu.Lock()
+	defer observerMu.Unlock()
+	observer = o
+}
+
+func stepObserver() StepObserver {
+	observerMu.RLock()
+	defer observerMu.RUnlock()
+	return observer
+}
+
+// Step is a named phase of a command, such as pushing the repository, whose start and end are logged
+// as events with its duration
+type Step struct {
+	name     string
+	started  time.Time
+	done     bool
+	status   string
+	duration time.Duration
+}
+
+// StartStep logs the start of the step called name
+func StartStep(name string) *Step {
+	GetLogger().Event("step_start", "step", name)
+	if o := stepObserver(); o != nil {
+		o.StepStarted(name)
+	}
+	return &Step{name: name, started: time.Now()}
+}
+
+// Name returns the name of the step
+func (s *Step) Name() string {
+	return s.name
+}
+
+// Status returns ok, failed or cancelled once the step finished, and an empty string before
+func (s *Step) Status() string {
+	return s.status
+}
+
+// Duration returns how long the finished step took
+func (s *Step) Duration() time.Duration {
+	return s.duration
+}
+
+// Done logs that the step succeeded. Calling it on a finished step does nothing
+func (s *Step) Done() {
+	s.finish("ok", nil)
+}
+
+// Fail logs that the step failed with err, or was cancelled when err is a context error. Calling it
+// on a finished step does nothing
+func (s *Step) Fail(err error) {
+	status := "failed"
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		status = "cancelled"
+	}
+	s.finish(status, err)
+}
+
+func (s *Step) finish(status string, err error) {
+	if s == nil || s.done {
+		return
+	}
+	s.done = true
+	s.status = status
+	s.duration = time.Since(s.started)
+
+	keyvals := []interface{}{
+		"step", s.name,
+		"status", status,
+		"duration_ms", s.duration.Milliseconds(),
+	}
+	if err != nil {
+		keyvals = append(keyvals, "error", err.Error())
+	}
+	GetLogger().Event("step_finish", keyvals...)
+
+	if o := stepObserver(); o != nil {
+		o.StepFinished(s.name, status, s.duration, err)
+	}
+}
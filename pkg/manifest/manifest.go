@@ -0,0 +1,151 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/layout"
+	"github.com/blazity/enterprise-cli/pkg/version"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the manifest file, relative to the repository root
+const FileName = ".enterprise.yaml"
+
+// SchemaVersion is the manifest schema version written by this CLI
+const SchemaVersion = 1
+
+// ErrNotFound is returned when a repository has no manifest
+var ErrNotFound = errors.New("no " + FileName + " found, was this repository prepared with the CLI?")
+
+// Manifest records the choices made when a repository was prepared, so later
+// commands can act on the repository without asking for them again.
+// Environments lists every GitHub Environment prepare created, so destroy can delete them.
+type Manifest struct {
+	SchemaVersion  int       `yaml:"schemaVersion"`
+	CLIVersion     string    `yaml:"cliVersion"`
+	PreparedAt     time.Time `yaml:"preparedAt,omitempty"`
+	Provider       string    `yaml:"provider"`
+	Region         string    `yaml:"region,omitempty"`
+	Bucket         string    `yaml:"bucket,omitempty"`
+	Project        string    `yaml:"project,omitempty"`
+	Repository     string    `yaml:"repository,omitempty"`
+	Branch         string    `yaml:"branch,omitempty"`
+	DeployWorkflow string    `yaml:"deployWorkflow,omitempty"`
+	Environments   []string  `yaml:"environments,omitempty"`
+	Secrets        Secrets   `yaml:"secrets,omitempty"`
+	Layout         Layout    `yaml:"layout"`
+	Template       Template  `yaml:"template"`
+}
+
+// Secrets records where the provider's secrets and variables were stored
+type Secrets struct {
+	Scope       string `yaml:"scope"`
+	Environment string `yaml:"environment,omitempty"`
+}
+
+// Layout records where the application and the infrastructure were placed
+type Layout struct {
+	AppDir     string   `yaml:"appDir"`
+	InfraDir   string   `yaml:"infraDir"`
+	Workspace  string   `yaml:"workspace,omitempty"`
+	KeepAtRoot []string `yaml:"keepAtRoot,omitempty"`
+}
+
+// Template identifies the template version the repository was rendered from
+type Template struct {
+	Source string `yaml:"source"`
+	Commit string `yaml:"commit,omitempty"`
+	SHA256 string `yaml:"sha256,omitempty"`
+}
+
+// New returns a manifest for the running CLI version
+func New(provider string) *Manifest {
+	return &Manifest{
+		SchemaVersion: SchemaVersion,
+		CLIVersion:    version.Version,
+		PreparedAt:    time.Now().UTC().Truncate(time.Second),
+		Provider:      provider,
+	}
+}
+
+// Organization returns the owner part of the repository
+func (m *Manifest) Organization() string {
+	owner, _, _ := strings.Cut(m.Repository, "/")
+	return owner
+}
+
+// RepositoryName returns the name part of the repository
+func (m *Manifest) RepositoryName() string {
+	_, name, _ := strings.Cut(m.Repository, "/")
+	return name
+}
+
+// RepositoryLayout returns the recorded layout, defaulting to the template layout for older manifests
+func (m *Manifest) RepositoryLayout() (layout.Layout, error) {
+	l := layout.Layout{
+		AppDir:     m.Layout.AppDir,
+		InfraDir:   m.Layout.InfraDir,
+		Workspace:  layout.Workspace(m.Layout.Workspace),
+		KeepAtRoot: m.Layout.KeepAtRoot,
+	}
+	if err := l.Normalize(); err != nil {
+		return l, fmt.Errorf("invalid layout in %s: %w", FileName, err)
+	}
+	return l, nil
+}
+
+// Read loads the manifest of the repository at repoDir, refusing schema versions
+// newer than this CLI supports
+func Read(repoDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(repoDir, FileName))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+
+	var header struct {
+		SchemaVersion int    `yaml:"schemaVersion"`
+		CLIVersion    string `yaml:"cliVersion"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", FileName, err)
+	}
+	switch {
+	case header.SchemaVersion == 0:
+		return nil, fmt.Errorf("%s has no schemaVersion", FileName)
+	case header.SchemaVersion > SchemaVersion:
+		return nil, fmt.Errorf("%s uses schema version %d, written by CLI %s; this CLI (%s) supports up to version %d, please update it",
+			FileName, header.SchemaVersion, header.CLIVersion, version.Version, SchemaVersion)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", FileName, err)
+	}
+	return &m, nil
+}
+
+// Write stores m in repoDir with the current schema and CLI version, and returns the paths that changed
+func Write(repoDir string, m *Manifest) ([]string, error) {
+	m.SchemaVersion = SchemaVersion
+	m.CLIVersion = version.Version
+
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", FileName, err)
+	}
+
+	path := filepath.Join(repoDir, FileName)
+	content := append([]byte("# Written by enterprise-cli, read back by deploy, status and upgrade\n"), data...)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", FileName, err)
+	}
+	return []string{path}, nil
+}
@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// answers reply to the configuration and credentials forms, so preparation runs without prompting.
+// Credentials left out of the file are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+type answers struct {
+	BucketName         string `yaml:"bucket-name"`
+	ProjectName        string `yaml:"project-name"`
+	Region             string `yaml:"region"`
+	RepositoryName     string `yaml:"repository-name"`
+	RepositoryOwner    string `yaml:"repository-owner"`
+	Private            *bool  `yaml:"private"`
+	AWSAccessKeyID     string `yaml:"aws-access-key-id"`
+	AWSSecretAccessKey string `yaml:"aws-secret-access-key"`
+}
+
+// loadAnswers reads the answers file at path, rejecting unknown keys so a misspelled answer is not ignored
+func loadAnswers(path string) (*answers, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read answers file: %w", err)
+	}
+
+	var a answers
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&a); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse answers file %s: %w", path, err)
+	}
+	return &a, nil
+}
+
+// applyConfig answers the configuration form, validating the answers like the form does.
+// The repository owner defaults to the first of organizations, the current user
+func (a *answers) applyConfig(p *AwsProvider, organizations []string) error {
+	if err := validateBucketName(a.BucketName); err != nil {
+		return fmt.Errorf("invalid bucket-name in the answers file: %w", err)
+	}
+	if err := validateProjectName(a.ProjectName); err != nil {
+		return fmt.Errorf("invalid project-name in the answers file: %w", err)
+	}
+
+	regions := make([]string, 0, len(regionOptions))
+	for _, option := range regionOptions {
+		regions = append(regions, option.Value)
+	}
+	if !slices.Contains(regions, a.Region) {
+		return fmt.Errorf("invalid region %q in the answers file, expected an AWS region such as us-east-1", a.Region)
+	}
+
+	if a.RepositoryName == "" {
+		return fmt.Errorf("the answers file needs a repository-name")
+	}
+
+	owner := a.RepositoryOwner
+	if owner == "" {
+		owner = organizations[0]
+	}
+	if !slices.Contains(organizations, owner) {
+		return fmt.Errorf("repository-owner %q in the answers file is neither the current user nor one of their organizations", owner)
+	}
+
+	p.bucketName = a.BucketName
+	p.projectName = a.ProjectName
+	p.region = a.Region
+	p.repositoryName = a.RepositoryName
+	p.organization = owner
+	p.isPrivate = a.Private == nil || *a.Private
+	return nil
+}
+
+// applyCredentials answers the credentials form
+func (a *answers) applyCredentials(p *AwsProvider) error {
+	p.accessKeyID = a.AWSAccessKeyID
+	if p.accessKeyID == "" {
+		p.accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	p.secretAccessKey = a.AWSSecretAccessKey
+	if p.secretAccessKey == "" {
+		p.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	if p.accessKeyID == "" || p.secretAccessKey == "" {
+		return fmt.Errorf("the answers file needs aws-access-key-id and aws-secret-access-key, or set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return nil
+}
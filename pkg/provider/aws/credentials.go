@@ -0,0 +1,196 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/secrets"
+)
+
+// RotateCredentials creates a new access key for the deploying IAM user, uploads it to the repository
+// secrets, waits until AWS accepts it and then deletes, or deactivates, the old key
+func (p *AwsProvider) RotateCredentials(ctx context.Context, opts provider.RotateOptions) error {
+	logger := logging.GetLogger()
+
+	m, err := manifest.Read(".")
+	if err != nil {
+		return err
+	}
+	if err := p.loadManifest(m); err != nil {
+		return err
+	}
+
+	loadOptions := []func(*config.LoadOptions) error{config.WithRegion(p.region)}
+	if opts.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(opts.Profile))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS credentials: %w", err)
+	}
+
+	iamClient := iam.NewFromConfig(cfg, func(o *iam.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
+
+	user := opts.User
+	if user == "" {
+		out, err := iamClient.GetUser(ctx, &iam.GetUserInput{})
+		if err != nil {
+			return fmt.Errorf("failed to determine the IAM user, pass one with --user: %w", err)
+		}
+		user = aws.ToString(out.User.UserName)
+	}
+
+	oldKeyID, err := p.oldAccessKey(ctx, iamClient, user, opts.OldKeyID)
+	if err != nil {
+		return err
+	}
+
+	created, err := iamClient.CreateAccessKey(ctx, &iam.CreateAccessKeyInput{UserName: aws.String(user)})
+	if err != nil {
+		return fmt.Errorf("failed to create a new access key for %s: %w", user, err)
+	}
+	newKeyID := aws.ToString(created.AccessKey.AccessKeyId)
+	newSecret := aws.ToString(created.AccessKey.SecretAccessKey)
+	logging.RegisterSecret(newSecret)
+	logger.Info("Created a new access key", "user", user, "key", maskKeyID(newKeyID))
+
+	client, err := secrets.NewClient()
+	if err == nil {
+		report := client.Store(ctx, opts.Target, []secrets.Entry{
+			secrets.Secret("AWS_ACCESS_KEY_ID", newKeyID),
+			secrets.Secret("AWS_SECRET_ACCESS_KEY", newSecret),
+		}, secrets.DefaultConcurrency)
+		err = report.Err()
+	}
+	if err != nil {
+		// The repository still holds the old key, so the new one is not needed
+		if _, deleteErr := iamClient.DeleteAccessKey(context.Background(), &iam.DeleteAccessKeyInput{
+			UserName:    aws.String(user),
+			AccessKeyId: aws.String(newKeyID),
+		}); deleteErr != nil {
+			logger.Warning("Failed to delete the unused new access key, remove it manually", "key", maskKeyID(newKeyID), "error", deleteErr)
+		}
+		return fmt.Errorf("failed to upload the new access key: %w", err)
+	}
+	logger.Info("Uploaded the new access key", "target", opts.Target)
+
+	if err := verifyAccessKey(ctx, cfg, opts.Endpoint, newKeyID, newSecret, opts.VerifyTimeout); err != nil {
+		return fmt.Errorf("the new access key was uploaded but not accepted by AWS, the old key %s is still active: %w", maskKeyID(oldKeyID), err)
+	}
+	logger.Info("Verified the new access key")
+
+	if oldKeyID == "" {
+		return nil
+	}
+
+	if opts.KeepOld {
+		if _, err := iamClient.UpdateAccessKey(ctx, &iam.UpdateAccessKeyInput{
+			UserName:    aws.String(user),
+			AccessKeyId: aws.String(oldKeyID),
+			Status:      iamtypes.StatusTypeInactive,
+		}); err != nil {
+			return fmt.Errorf("failed to deactivate the old access key %s: %w", maskKeyID(oldKeyID), err)
+		}
+		logger.Info("Deactivated the old access key", "key", maskKeyID(oldKeyID))
+		return nil
+	}
+
+	if _, err := iamClient.DeleteAccessKey(ctx, &iam.DeleteAccessKeyInput{
+		UserName:    aws.String(user),
+		AccessKeyId: aws.String(oldKeyID),
+	}); err != nil {
+		return fmt.Errorf("failed to delete the old access key %s: %w", maskKeyID(oldKeyID), err)
+	}
+	logger.Info("Deleted the old access key", "key", maskKeyID(oldKeyID))
+	return nil
+}
+
+// oldAccessKey returns the key of user being replaced. IAM users hold at most two keys, so
+// without an explicit key the user must have a single one
+func (p *AwsProvider) oldAccessKey(ctx context.Context, client *iam.Client, user string, keyID string) (string, error) {
+	out, err := client.ListAccessKeys(ctx, &iam.ListAccessKeysInput{UserName: aws.String(user)})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the access keys of %s: %w", user, err)
+	}
+
+	if keyID != "" {
+		for _, key := range out.AccessKeyMetadata {
+			if aws.ToString(key.AccessKeyId) == keyID {
+				if len(out.AccessKeyMetadata) > 1 {
+					return "", fmt.Errorf("%s already has two access keys, delete the one not in use before rotating", user)
+				}
+				return keyID, nil
+			}
+		}
+		return "", fmt.Errorf("access key %s does not belong to %s", maskKeyID(keyID), user)
+	}
+
+	switch len(out.AccessKeyMetadata) {
+	case 0:
+		return "", nil
+	case 1:
+		return aws.ToString(out.AccessKeyMetadata[0].AccessKeyId), nil
+	default:
+		return "", fmt.Errorf("%s already has two access keys, delete the one not in use before rotating", user)
+	}
+}
+
+// verifyAccessKey retries an authenticated call with the new key until IAM has propagated it
+func verifyAccessKey(ctx context.Context, cfg aws.Config, endpoint string, keyID string, secret string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+
+	cfg = cfg.Copy()
+	cfg.Credentials = credentials.NewStaticCredentialsProvider(keyID, secret, "")
+	client := sts.NewFromConfig(cfg, func(o *sts.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := 2 * time.Second
+	for {
+		_, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err == nil {
+			return nil
+		}
+		logging.GetLogger().Debug("New access key not accepted yet", "error", err)
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timed out after %s: %w", timeout, err)
+			}
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 15*time.Second)
+	}
+}
+
+// maskKeyID keeps enough of an access key ID to recognise it in the IAM console
+func maskKeyID(keyID string) string {
+	if len(keyID) <= 8 {
+		return keyID
+	}
+	return keyID[:4] + "…" + keyID[len(keyID)-4:]
+}
@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/runner"
+)
+
+// terraformRootDir is the Terraform configuration of the template, below the infrastructure directory,
+// whose backend the HCL codemod points at the recorded bucket
+const terraformRootDir = "dev"
+
+// DestroyWithOptions tears down what prepare and deploy created, in the reverse order: the Terraform
+// resources, the bucket holding their state, the GitHub Environments and finally the repository.
+// The local checkout is left untouched.
+func (p *AwsProvider) DestroyWithOptions(ctx context.Context, opts provider.DestroyOptions) (*provider.DestroySummary, error) {
+	logger := logging.GetLogger()
+
+	m, err := manifest.Read(".")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.loadManifest(m); err != nil {
+		return nil, err
+	}
+
+	summary := &provider.DestroySummary{}
+
+	if !opts.KeepInfrastructure {
+		dir := filepath.Join(p.layout.InfraDir, terraformRootDir)
+		if err := p.destroyTerraform(ctx, dir, opts.Profile); err != nil {
+			return summary, err
+		}
+		summary.Infrastructure = append(summary.Infrastructure, dir)
+		logger.Info("Destroyed the infrastructure", "dir", dir)
+
+		if p.bucketName != "" {
+			if err := p.deleteBucket(ctx, opts.Profile); err != nil {
+				return summary, err
+			}
+			summary.Bucket = p.bucketName
+			logger.Info("Deleted the Terraform state bucket", "bucket", p.bucketName)
+		}
+	}
+
+	if m.Repository == "" {
+		return summary, nil
+	}
+
+	for _, environment := range m.Environments {
+		if err := github.DeleteEnvironment(ctx, m.Repository, environment); err != nil {
+			return summary, fmt.Errorf("failed to delete environment %s: %w", environment, err)
+		}
+		summary.Environments = append(summary.Environments, environment)
+		logger.Info("Deleted environment", "name", environment)
+	}
+
+	if !opts.KeepRepository {
+		if err := github.DeleteRepository(ctx, m.Repository); err != nil {
+			return summary, err
+		}
+		summary.Repository = m.Repository
+		logger.Info("Deleted repository", "repo", m.Repository)
+	}
+
+	return summary, nil
+}
+
+// destroyTerraform initializes the Terraform configuration in dir against its recorded backend and
+// destroys every resource of its state
+func (p *AwsProvider) destroyTerraform(ctx context.Context, dir string, profile string) error {
+	env := []string{"AWS_REGION=" + p.region}
+	if profile != "" {
+		env = append(env, "AWS_PROFILE="+profile)
+	}
+
+	steps := [][]string{
+		{"init", "-input=false"},
+		{"destroy", "-auto-approve", "-input=false"},
+	}
+	for _, args := range steps {
+		cmd := runner.Command{Name: "terraform", Args: append([]string{"-chdir=" + dir}, args...), Env: env}
+		logging.GetLogger().Debug("Running terraform", "command", cmd.String())
+
+		result, err := runner.Run(ctx, cmd)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, exec.ErrNotFound) {
+				return fmt.Errorf("command 'terraform' not found in PATH, install it or pass --keep-infrastructure: %w", err)
+			}
+			return fmt.Errorf("%s failed: %w\nOutput:\n%s", cmd, err, string(result.Combined()))
+		}
+	}
+	return nil
+}
+
+// deleteBucket empties the Terraform state bucket, including the old versions of the state, and deletes it.
+// A bucket that no longer exists is not an error
+func (p *AwsProvider) deleteBucket(ctx context.Context, profile string) error {
+	loadOptions := []func(*config.LoadOptions) error{config.WithRegion(p.region)}
+	if profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(profile))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS credentials: %w", err)
+	}
+	client := s3.NewFromConfig(cfg)
+
+	var noSuchBucket *s3types.NoSuchBucket
+	paginator := s3.NewListObjectVersionsPaginator(client, &s3.ListObjectVersionsInput{Bucket: aws.String(p.bucketName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if errors.As(err, &noSuchBucket) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list the objects of bucket %s: %w", p.bucketName, err)
+		}
+
+		var objects []s3types.ObjectIdentifier
+		for _, version := range page.Versions {
+			objects = append(objects, s3types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, s3types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) == 0 {
+			continue
+		}
+
+		// A page holds at most 1000 versions and markers each, the most a single request deletes
+		for start := 0; start < len(objects); start += 1000 {
+			batch := objects[start:min(start+1000, len(objects))]
+			out, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(p.bucketName),
+				Delete: &s3types.Delete{Objects: batch, Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to empty bucket %s: %w", p.bucketName, err)
+			}
+			if len(out.Errors) > 0 {
+				return fmt.Errorf("failed to delete %s from bucket %s: %s", aws.ToString(out.Errors[0].Key), p.bucketName, aws.ToString(out.Errors[0].Message))
+			}
+		}
+	}
+
+	if _, err := client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(p.bucketName)}); err != nil && !errors.As(err, &noSuchBucket) {
+		return fmt.Errorf("failed to delete bucket %s: %w", p.bucketName, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/ui"
+	"github.com/charmbracelet/huh"
+)
+
+// dirtyTreeQuestion is the wording of the preflight question for an operation
+type dirtyTreeQuestion struct {
+	description string
+	worktree    string
+	stash       string
+}
+
+var prepareDirtyTreeQuestion = dirtyTreeQuestion{
+	description: "Preparing commits and moves files, pick how your changes are kept out of it",
+	worktree:    "Work in a temporary worktree, then fast-forward (changes stay in place)",
+	stash:       "Stash my changes (restored if preparation fails)",
+}
+
+// preflight inspects the working tree and settles how preparation deals with uncommitted changes
+func (p *AwsProvider) preflight(policy provider.DirtyTreePolicy) error {
+	return p.preflightFor(policy, prepareDirtyTreeQuestion)
+}
+
+// preflightFor settles how an operation writing to the working tree deals with uncommitted changes,
+// asking question when policy is DirtyTreePrompt
+func (p *AwsProvider) preflightFor(policy provider.DirtyTreePolicy, question dirtyTreeQuestion) error {
+	logger := logging.GetLogger()
+
+	status, err := github.GetWorkingTreeStatus(".")
+	if err != nil {
+		return err
+	}
+	if status.IsClean() {
+		return nil
+	}
+
+	paths := status.Paths()
+	logger.Warning("The working tree has uncommitted changes", "staged", len(status.Staged), "unstaged", len(status.Unstaged), "untracked", len(status.Untracked))
+	logger.Debug("Uncommitted changes", "paths", paths)
+
+	if policy == provider.DirtyTreePrompt {
+		policy = provider.DirtyTreeWorktree
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[provider.DirtyTreePolicy]().
+					Title(fmt.Sprintf("%d files have uncommitted changes", len(paths))).
+					Description(question.description).
+					Options(
+						huh.NewOption(question.worktree, provider.DirtyTreeWorktree),
+						huh.NewOption(question.stash, provider.DirtyTreeStash),
+						huh.NewOption("Abort", provider.DirtyTreeAbort),
+					).
+					Value(&policy),
+			),
+		)
+		if err := ui.RunForm(form, p.cancel); err != nil {
+			if errors.Is(err, ui.ErrFormCancelled) {
+				p.cancelled = true
+			}
+			return err
+		}
+	}
+
+	if policy == provider.DirtyTreeAbort {
+		return fmt.Errorf("the working tree has uncommitted changes, commit or stash them, or rerun with --dirty-tree=stash or --dirty-tree=worktree")
+	}
+
+	p.dirtyTree = policy
+	return nil
+}
+
+// startBranch creates the branch preparation commits to, honouring the dirty tree policy.
+// In worktree mode the process moves into a temporary worktree until leaveWorktree is called.
+func (p *AwsProvider) startBranch(ctx context.Context, opts github.BranchOptions) (string, error) {
+	logger := logging.GetLogger()
+
+	switch p.dirtyTree {
+	case provider.DirtyTreeStash:
+		stash, err := github.Stash(opts.Path, "enterprise-cli: changes saved before prepare")
+		if err != nil {
+			return "", err
+		}
+		p.stash = stash
+		logger.Info("Stashed uncommitted changes", "stash", stash)
+
+	case provider.DirtyTreeWorktree:
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		dir, err := os.MkdirTemp("", "enterprise-worktree-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+
+		branch := fmt.Sprintf("%s-%d", opts.BranchName, time.Now().Unix())
+		if err := github.AddWorktree(opts.Path, dir, branch, opts.BaseBranch); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		if err := os.Chdir(dir); err != nil {
+			github.RemoveWorktree(cwd, dir)
+			return "", fmt.Errorf("failed to enter worktree: %w", err)
+		}
+
+		p.originalDir = cwd
+		p.worktreeDir = dir
+		logger.Info("Working in a temporary worktree, your checkout is left untouched", "path", dir)
+		return branch, nil
+	}
+
+	return github.CreateBranch(ctx, opts)
+}
+
+// leaveWorktree returns to the original checkout and removes the temporary worktree, keeping its branch
+func (p *AwsProvider) leaveWorktree() error {
+	if p.worktreeDir == "" {
+		return nil
+	}
+
+	if err := os.Chdir(p.originalDir); err != nil {
+		return fmt.Errorf("failed to return to %s: %w", p.originalDir, err)
+	}
+	dir := p.worktreeDir
+	p.worktreeDir = ""
+	return github.RemoveWorktree(".", dir)
+}
+
+// mergeBack fast-forwards baseBranch in the original checkout to the prepared branch.
+// It returns false, keeping the prepared branch, when that is not a fast-forward.
+func (p *AwsProvider) mergeBack(baseBranch string) bool {
+	logger := logging.GetLogger()
+
+	current, err := github.GetCurrentBranch(".")
+	if err != nil {
+		return false
+	}
+
+	if current == baseBranch {
+		err = github.FastForward(".", p.activeBranch)
+	} else {
+		// Updates the branch ref without touching the checkout, refusing anything but a fast-forward
+		var out []byte
+		out, err = git(context.Background(), "fetch", ".", fmt.Sprintf("%s:%s", p.activeBranch, baseBranch))
+		if err != nil {
+			logger.Debug(string(out))
+		}
+	}
+	if err != nil {
+		logger.Warning(fmt.Sprintf("Could not fast-forward %s, merge the prepared branch yourself", baseBranch), "branch", p.activeBranch, "error", err)
+		return false
+	}
+
+	logger.Info("Fast-forwarded the prepared commits into your checkout", "branch", baseBranch)
+	return true
+}
@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"github.com/blazity/enterprise-cli/pkg/layout"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+)
+
+// Steps of a preparation and a deployment, in the order they run
+const (
+	stepFetchTemplate       = "Fetch template"
+	stepCreateBranch        = "Create branch"
+	stepCopyGitHubActions   = "Copy GitHub Actions"
+	stepCopyTerraform       = "Copy Terraform"
+	stepHclCodemod          = "Apply HCL codemod"
+	stepNextConfigCodemod   = "Apply next.config codemod"
+	stepPackageJsonCodemod  = "Apply package.json codemod"
+	stepCopyResources       = "Copy resources"
+	stepMoveApplication     = "Move application"
+	stepSetUpWorkspace      = "Set up workspace"
+	stepFinishCommits       = "Finish commits"
+	stepCreateRepository    = "Create repository"
+	stepStoreSecrets        = "Store secrets"
+	stepEnableGitHubActions = "Enable GitHub Actions"
+	stepPushBranch          = "Push branch"
+	stepHardenRepository    = "Harden repository"
+	stepCheckOutBaseBranch  = "Check out base branch"
+
+	stepDispatchWorkflow = "Dispatch workflow"
+	stepWaitForRun       = "Wait for run"
+)
+
+// PrepareSteps lists the steps a preparation with opts runs
+func (p *AwsProvider) PrepareSteps(opts provider.PrepareOptions) []string {
+	steps := []string{
+		stepFetchTemplate,
+		stepCreateBranch,
+		stepCopyGitHubActions,
+		stepCopyTerraform,
+		stepHclCodemod,
+		stepNextConfigCodemod,
+		stepPackageJsonCodemod,
+		stepCopyResources,
+		stepMoveApplication,
+	}
+	if opts.Workspace != "" && layout.Workspace(opts.Workspace) != layout.WorkspaceNone {
+		steps = append(steps, stepSetUpWorkspace)
+	}
+	steps = append(steps, stepFinishCommits, stepCreateRepository, stepStoreSecrets, stepEnableGitHubActions, stepPushBranch)
+	if opts.Harden {
+		steps = append(steps, stepHardenRepository)
+	}
+	return append(steps, stepCheckOutBaseBranch)
+}
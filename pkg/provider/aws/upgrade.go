@@ -0,0 +1,473 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/codemod"
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/layout"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/manifest"
+	"github.com/blazity/enterprise-cli/pkg/provider"
+	"github.com/blazity/enterprise-cli/pkg/resources"
+	"github.com/blazity/enterprise-cli/pkg/templates"
+	"github.com/blazity/enterprise-cli/pkg/utils/filesystem"
+)
+
+// UpgradeWithOptions renders the recorded and the requested template versions the same way
+// prepare does, and three-way merges the upstream changes into the repository files.
+// The next.config.ts and package.json codemods only touch application code, which does
+// not come from the template, so they are not part of the rendered trees.
+// Changes are left in the working tree for review, with conflict markers where both sides changed.
+// Uncommitted changes are first kept out of the way like prepare does, by stashing them or by
+// upgrading in a temporary worktree whose result is committed to a new branch.
+func (p *AwsProvider) UpgradeWithOptions(ctx context.Context, opts provider.UpgradeOptions) (*provider.UpgradeSummary, error) {
+	dirtyTreePolicy, err := provider.ParseDirtyTreePolicy(opts.DirtyTree)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.preflightFor(dirtyTreePolicy, upgradeDirtyTreeQuestion); err != nil {
+		return nil, err
+	}
+
+	branch, err := p.enterUpgradeTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := p.upgrade(ctx, opts)
+	return summary, p.leaveUpgradeTree(ctx, branch, summary, err)
+}
+
+var upgradeDirtyTreeQuestion = dirtyTreeQuestion{
+	description: "Upgrading merges template changes into your files, pick how your changes are kept out of it",
+	worktree:    "Upgrade in a temporary worktree on a new branch (changes stay in place)",
+	stash:       "Stash my changes (restored if the upgrade fails)",
+}
+
+// enterUpgradeTree sets uncommitted changes aside as settled by the preflight, returning the branch
+// the upgrade is written to when it runs in a temporary worktree
+func (p *AwsProvider) enterUpgradeTree(ctx context.Context) (string, error) {
+	switch p.dirtyTree {
+	case provider.DirtyTreeStash:
+		stash, err := github.Stash(".", "enterprise-cli: changes saved before upgrade")
+		if err != nil {
+			return "", err
+		}
+		p.stash = stash
+		logging.GetLogger().Info("Stashed uncommitted changes", "stash", stash)
+
+	case provider.DirtyTreeWorktree:
+		return p.startBranch(ctx, github.BranchOptions{Path: ".", BranchName: "enterprise-template-upgrade", BaseBranch: "HEAD"})
+	}
+	return "", nil
+}
+
+// leaveUpgradeTree brings back what enterUpgradeTree set aside. A successful upgrade in a worktree is
+// committed to branch, and the worktree is kept when conflicts are left to resolve in it
+func (p *AwsProvider) leaveUpgradeTree(ctx context.Context, branch string, summary *provider.UpgradeSummary, upgradeErr error) error {
+	logger := logging.GetLogger()
+
+	switch p.dirtyTree {
+	case provider.DirtyTreeStash:
+		if upgradeErr == nil {
+			summary.Stash = p.stash
+		} else if err := github.StashPop(".", p.stash); err != nil {
+			logger.Warning("Failed to restore stashed changes", "stash", p.stash, "error", err)
+		}
+		p.stash = ""
+
+	case provider.DirtyTreeWorktree:
+		if upgradeErr == nil && len(summary.Conflicts) > 0 {
+			// The worktree stays for the conflicts to be resolved there
+			summary.Branch = branch
+			summary.Worktree = p.worktreeDir
+			p.worktreeDir = ""
+			if err := os.Chdir(p.originalDir); err != nil {
+				return fmt.Errorf("failed to return to %s: %w", p.originalDir, err)
+			}
+			return nil
+		}
+
+		keep := false
+		if upgradeErr == nil {
+			var err error
+			if keep, err = commitUpgrade(ctx, summary); err != nil {
+				upgradeErr = err
+			}
+		}
+		if err := p.leaveWorktree(); err != nil {
+			logger.Warning("Failed to remove the temporary worktree", "error", err)
+		}
+		if keep {
+			summary.Branch = branch
+		} else if out, err := git(context.Background(), "branch", "-D", branch); err != nil {
+			logger.Warning("Failed to delete the upgrade branch", "branch", branch, "error", err)
+			logger.Debug(string(out))
+		}
+	}
+
+	return upgradeErr
+}
+
+// commitUpgrade commits the upgraded files in the worktree, reporting false when nothing changed
+func commitUpgrade(ctx context.Context, summary *provider.UpgradeSummary) (bool, error) {
+	status, err := github.GetWorkingTreeStatus(".")
+	if err != nil {
+		return false, err
+	}
+	if status.IsClean() {
+		return false, nil
+	}
+
+	if out, err := git(ctx, "add", "-A"); err != nil {
+		logging.GetLogger().Debug(string(out))
+		return false, fmt.Errorf("failed to stage the upgraded files: %w", err)
+	}
+	message := "chore: upgrade template"
+	if summary.ToCommit != "" {
+		message += " to " + summary.ToCommit
+	}
+	if out, err := git(ctx, "commit", "-m", message); err != nil {
+		logging.GetLogger().Debug(string(out))
+		return false, fmt.Errorf("failed to commit the upgrade: %w", err)
+	}
+	return true, nil
+}
+
+// upgrade fetches both template versions and merges their differences into the working tree
+func (p *AwsProvider) upgrade(ctx context.Context, opts provider.UpgradeOptions) (*provider.UpgradeSummary, error) {
+	logger := logging.GetLogger()
+
+	m, err := manifest.Read(".")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.loadManifest(m); err != nil {
+		return nil, err
+	}
+
+	oldSource, err := templates.ParseSource(m.Template.Source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recorded template source: %w", err)
+	}
+
+	newValue := opts.Template
+	if newValue == "" {
+		newValue = m.Template.Source
+	}
+	newSource, err := templates.ParseSource(newValue)
+	if err != nil {
+		return nil, err
+	}
+
+	if oldSource.Kind == templates.KindGitHub && m.Template.Commit != "" {
+		oldSource.Ref = m.Template.Commit
+	}
+
+	workDir, err := os.MkdirTemp("", "enterprise-upgrade-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	fetchOpts := templates.FetchOptions{}
+	if cache, err := templates.NewCache(); err == nil {
+		fetchOpts.Cache = cache
+	}
+
+	logger.Info("Fetching the recorded template version", "source", oldSource.String())
+	oldOpts := fetchOpts
+	oldOpts.PinnedSHA256 = m.Template.SHA256
+	oldTemplate, err := templates.Fetch(ctx, oldSource, filepath.Join(workDir, "old-template"), oldOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the template version this repository was prepared with: %w", err)
+	}
+
+	logger.Info("Fetching the new template version", "source", newSource.String())
+	newOpts := fetchOpts
+	newOpts.PinnedCommit = opts.TemplateCommit
+	newOpts.PinnedSHA256 = opts.TemplateSHA256
+	newTemplate, err := templates.Fetch(ctx, newSource, filepath.Join(workDir, "new-template"), newOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the new template version: %w", err)
+	}
+
+	summary := &provider.UpgradeSummary{
+		FromCommit: oldTemplate.Commit,
+		ToCommit:   newTemplate.Commit,
+	}
+
+	if oldTemplate.SHA256 == newTemplate.SHA256 {
+		logger.Info("The template has not changed, nothing to upgrade")
+		return summary, nil
+	}
+
+	oldRendered := filepath.Join(workDir, "old")
+	newRendered := filepath.Join(workDir, "new")
+	if err := p.renderTemplate(oldTemplate.Dir, oldRendered); err != nil {
+		return nil, fmt.Errorf("failed to render the recorded template version: %w", err)
+	}
+	if err := p.renderTemplate(newTemplate.Dir, newRendered); err != nil {
+		return nil, fmt.Errorf("failed to render the new template version: %w", err)
+	}
+
+	err = mergeRenderedTrees(ctx, oldRendered, newRendered, labelFor(oldTemplate), labelFor(newTemplate), p.repositoryPath, summary)
+	for _, paths := range [][]string{summary.Updated, summary.Added, summary.Removed, summary.Merged, summary.Conflicts, summary.Skipped} {
+		sort.Strings(paths)
+	}
+	if err != nil {
+		return summary, err
+	}
+
+	m.Template = manifest.Template{
+		Source: newTemplate.Source.String(),
+		Commit: newTemplate.Commit,
+		SHA256: newTemplate.SHA256,
+	}
+	if _, err := manifest.Write(".", m); err != nil {
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// renderTemplate reproduces in outDir the files prepare derives from the template in templateDir
+func (p *AwsProvider) renderTemplate(templateDir, outDir string) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	copies := map[string]string{
+		".github":               ".github",
+		layout.TemplateInfraDir: p.layout.InfraDir,
+	}
+	for from, to := range copies {
+		src := filepath.Join(templateDir, from)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := filesystem.CopyDir(src, filepath.Join(outDir, to)); err != nil {
+			return err
+		}
+	}
+
+	workflowCodemodCfg := codemod.NewDefaultWorkflowCodemodConfig()
+	workflowCodemodCfg.WorkflowsDir = filepath.Join(outDir, ".github", "workflows")
+	workflowCodemodCfg.PathRewrites = p.layout.PathRewrites()
+	if _, err := codemod.RunWorkflowCodemod(workflowCodemodCfg); err != nil {
+		return fmt.Errorf("failed to rewrite workflow paths: %w", err)
+	}
+
+	if err := codemod.RunHclCodemod(p.hclCodemodConfig(filepath.Join(outDir, p.layout.InfraDir))); err != nil {
+		return fmt.Errorf("failed to apply HCL codemod: %w", err)
+	}
+
+	resourceManager, err := resources.NewResourceManager(templateDir, p.resourceVariables())
+	if err != nil {
+		return err
+	}
+	resourceManager.SetDestinationRoot(outDir)
+	resourceManager.SetPathRewrites(p.layout.PathRewrites())
+	if _, err := resourceManager.CopyAllMappings(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// repositoryPath maps a path of a rendered tree to its location in the prepared repository,
+// where everything but the root entries was moved into the application directory
+func (p *AwsProvider) repositoryPath(rel string) string {
+	first := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+	for _, entry := range p.layout.RootEntries(manifest.FileName) {
+		if first == entry {
+			return rel
+		}
+	}
+	return filepath.Join(p.layout.AppDir, rel)
+}
+
+func labelFor(resolved *templates.Resolved) string {
+	if resolved.Commit != "" {
+		short := resolved.Commit
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		return "template@" + short
+	}
+	return "template@" + resolved.SHA256[:12]
+}
+
+// mergeRenderedTrees applies the differences between the old and new rendered trees to the repository
+func mergeRenderedTrees(ctx context.Context, oldDir, newDir, oldLabel, newLabel string, repositoryPath func(string) string, summary *provider.UpgradeSummary) error {
+	logger := logging.GetLogger()
+
+	oldFiles, err := listFiles(oldDir)
+	if err != nil {
+		return err
+	}
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return err
+	}
+
+	all := map[string]bool{}
+	for rel := range oldFiles {
+		all[rel] = true
+	}
+	for rel := range newFiles {
+		all[rel] = true
+	}
+
+	for rel := range all {
+		oldPath := filepath.Join(oldDir, rel)
+		newPath := filepath.Join(newDir, rel)
+		repoPath := repositoryPath(rel)
+
+		oldContent, inOld := readIfExists(oldPath, oldFiles[rel])
+		newContent, inNew := readIfExists(newPath, newFiles[rel])
+		current, inRepo := readIfExists(repoPath, true)
+
+		if inOld && inNew && bytes.Equal(oldContent, newContent) {
+			continue
+		}
+
+		switch {
+		case !inOld && inNew:
+			if !inRepo {
+				if err := writeRepoFile(repoPath, newContent); err != nil {
+					return err
+				}
+				summary.Added = append(summary.Added, repoPath)
+				continue
+			}
+			if bytes.Equal(current, newContent) {
+				continue
+			}
+			emptyBase := filepath.Join(oldDir, ".empty-base")
+			if err := os.WriteFile(emptyBase, nil, 0644); err != nil {
+				return err
+			}
+			if err := mergeInto(ctx, repoPath, emptyBase, newPath, oldLabel, newLabel, summary); err != nil {
+				return err
+			}
+
+		case inOld && !inNew:
+			if !inRepo {
+				continue
+			}
+			if bytes.Equal(current, oldContent) {
+				if err := os.Remove(repoPath); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", repoPath, err)
+				}
+				summary.Removed = append(summary.Removed, repoPath)
+				continue
+			}
+			logger.Warning("File was removed from the template but modified locally, keeping it", "path", repoPath)
+			summary.Skipped = append(summary.Skipped, repoPath)
+
+		default:
+			if !inRepo {
+				logger.Warning("File was changed in the template but deleted locally, skipping it", "path", repoPath)
+				summary.Skipped = append(summary.Skipped, repoPath)
+				continue
+			}
+			if bytes.Equal(current, oldContent) {
+				if err := writeRepoFile(repoPath, newContent); err != nil {
+					return err
+				}
+				summary.Updated = append(summary.Updated, repoPath)
+				continue
+			}
+			if bytes.Equal(current, newContent) {
+				continue
+			}
+			if err := mergeInto(ctx, repoPath, oldPath, newPath, oldLabel, newLabel, summary); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeInto three-way merges the base..other changes into the repository file at repoPath
+func mergeInto(ctx context.Context, repoPath, basePath, otherPath, baseLabel, otherLabel string, summary *provider.UpgradeSummary) error {
+	merged, conflicts, err := github.MergeFile(ctx, repoPath, basePath, otherPath, "local", baseLabel, otherLabel)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		logging.GetLogger().Warning("Could not merge file, the new template version was saved next to it", "path", repoPath, "error", err)
+		content, readErr := os.ReadFile(otherPath)
+		if readErr != nil {
+			return readErr
+		}
+		if err := writeRepoFile(repoPath+".upstream", content); err != nil {
+			return err
+		}
+		summary.Conflicts = append(summary.Conflicts, repoPath)
+		return nil
+	}
+
+	if err := writeRepoFile(repoPath, merged); err != nil {
+		return err
+	}
+	if conflicts > 0 {
+		summary.Conflicts = append(summary.Conflicts, repoPath)
+	} else {
+		summary.Merged = append(summary.Merged, repoPath)
+	}
+	return nil
+}
+
+func listFiles(dir string) (map[string]bool, error) {
+	files := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+func readIfExists(path string, listed bool) ([]byte, bool) {
+	if !listed {
+		return nil, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+func writeRepoFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
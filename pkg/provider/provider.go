@@ -2,14 +2,206 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/secrets"
+)
+
+// PrepareOptions holds the command line choices passed to a provider's preparation
+type PrepareOptions struct {
+	// Template is the template source: owner/repo[@ref], a local directory or a .tar.gz archive
+	Template string
+	// TemplateCommit and TemplateSHA256 pin the expected template commit and content checksum
+	TemplateCommit string
+	TemplateSHA256 string
+	// AnswersFile is a YAML file answering the configuration and credential prompts, for non-interactive runs
+	AnswersFile string
+	// UntrackedFiles is the github.UntrackedPolicy applied to untracked files when the application is moved
+	UntrackedFiles string
+	// BaseBranch is the branch preparation starts from and pushes to, detected when empty
+	BaseBranch string
+	// DirtyTree is the DirtyTreePolicy applied when the working tree has uncommitted changes
+	DirtyTree string
+	// AppDir, InfraDir, Workspace and KeepAtRoot configure the layout.Layout of the prepared repository
+	AppDir     string
+	InfraDir   string
+	Workspace  string
+	KeepAtRoot []string
+	// Commit customizes signing, authorship and messages of the commits created
+	Commit github.CommitOptions
+	// SecretsScope is the secrets.Scope secrets and variables are stored in, with SecretsEnvironment
+	// naming the environment of the environment scope
+	SecretsScope       string
+	SecretsEnvironment string
+	// Harden applies Hardening to the repository once it is created
+	Harden    bool
+	Hardening github.HardeningOptions
+}
+
+// DirtyTreePolicy decides how preparation deals with uncommitted changes in the working tree
+type DirtyTreePolicy string
+
+const (
+	// DirtyTreePrompt asks the user to pick one of the other policies
+	DirtyTreePrompt DirtyTreePolicy = "prompt"
+	// DirtyTreeAbort refuses to prepare the repository
+	DirtyTreeAbort DirtyTreePolicy = "abort"
+	// DirtyTreeStash stashes the changes first, restoring them if preparation fails
+	DirtyTreeStash DirtyTreePolicy = "stash"
+	// DirtyTreeWorktree prepares the repository in a temporary git worktree and fast-forwards the result back
+	DirtyTreeWorktree DirtyTreePolicy = "worktree"
 )
 
+// DirtyTreePolicies lists the valid dirty tree policies
+var DirtyTreePolicies = []DirtyTreePolicy{DirtyTreePrompt, DirtyTreeAbort, DirtyTreeStash, DirtyTreeWorktree}
+
+// ParseDirtyTreePolicy validates s, defaulting to DirtyTreePrompt when empty
+func ParseDirtyTreePolicy(s string) (DirtyTreePolicy, error) {
+	if s == "" {
+		return DirtyTreePrompt, nil
+	}
+	names := make([]string, 0, len(DirtyTreePolicies))
+	for _, policy := range DirtyTreePolicies {
+		if DirtyTreePolicy(s) == policy {
+			return policy, nil
+		}
+		names = append(names, string(policy))
+	}
+	return "", fmt.Errorf("unknown dirty tree policy '%s', expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// DeployOptions holds the command line choices passed to a provider's deployment
+type DeployOptions struct {
+	// Workflow overrides the deploy workflow recorded in the manifest
+	Workflow string
+	// Ref is the branch or tag to deploy, defaulting to the recorded branch
+	Ref string
+	// Wait blocks until the triggered workflow run completes, up to Timeout
+	Wait    bool
+	Timeout time.Duration
+}
+
+// RunFailedError is returned when a workflow run triggered by a provider does not succeed
+type RunFailedError struct {
+	Repository string
+	Run        *github.WorkflowRun
+}
+
+func (e *RunFailedError) Error() string {
+	return fmt.Sprintf("workflow run %s finished with conclusion %q: %s", e.Run.ID, e.Run.Conclusion, e.Run.URL)
+}
+
 type Provider interface {
 	GetName() string
 	Prepare() error
 	PrepareWithContext(ctx context.Context) error
+	PrepareWithOptions(ctx context.Context, opts PrepareOptions) error
 	Deploy() error
 	DeployWithContext(ctx context.Context) error
+	DeployWithOptions(ctx context.Context, opts DeployOptions) error
+}
+
+// StepPlanner is implemented by providers that list the steps of a preparation up front, so its progress
+// can be shown before they run
+type StepPlanner interface {
+	PrepareSteps(opts PrepareOptions) []string
+}
+
+// TemplateVariables is implemented by providers whose templates can refer to prepare-time variables
+// in the conditions of their _map.yml mappings
+type TemplateVariables interface {
+	ResourceVariableNames() []string
+}
+
+// UpgradeOptions selects the template version a prepared repository is upgraded to
+type UpgradeOptions struct {
+	// Template is the new template source, defaulting to the recorded source
+	Template       string
+	TemplateCommit string
+	TemplateSHA256 string
+	// DirtyTree is the DirtyTreePolicy applied when the working tree has uncommitted changes
+	DirtyTree string
+}
+
+// UpgradeSummary lists the repository paths touched by an upgrade
+type UpgradeSummary struct {
+	FromCommit string
+	ToCommit   string
+	Updated    []string
+	Added      []string
+	Removed    []string
+	Merged     []string
+	Conflicts  []string
+	Skipped    []string
+	// Branch holds the upgrade when it ran in a temporary worktree, which is kept at Worktree while
+	// conflicts are left to resolve
+	Branch   string
+	Worktree string
+	// Stash holds the uncommitted changes set aside for the upgrade
+	Stash string
+}
+
+// Upgrader is implemented by providers that can upgrade the template files of a prepared repository
+type Upgrader interface {
+	UpgradeWithOptions(ctx context.Context, opts UpgradeOptions) (*UpgradeSummary, error)
+}
+
+// ManagedValue is a secret or variable the deployment workflows of a provider read
+type ManagedValue struct {
+	Name        string
+	Kind        secrets.Kind
+	Description string
+}
+
+// RotateOptions configures the rotation of the cloud credentials stored in the repository secrets
+type RotateOptions struct {
+	// Target is where the rotated credentials are uploaded
+	Target secrets.Target
+	// Endpoint overrides the identity service endpoint, for example to use a local emulator
+	Endpoint string
+	// Profile selects the local credentials profile used to perform the rotation
+	Profile string
+	// User is the identity whose key is rotated, defaulting to the caller
+	User string
+	// OldKeyID is the key being replaced, required when the user has several keys
+	OldKeyID string
+	// KeepOld deactivates the old key instead of deleting it
+	KeepOld bool
+	// VerifyTimeout bounds how long the new key is retried until it is accepted
+	VerifyTimeout time.Duration
+}
+
+// SecretsManager is implemented by providers whose secrets and variables can be managed after preparation
+type SecretsManager interface {
+	ManagedValues() []ManagedValue
+	RotateCredentials(ctx context.Context, opts RotateOptions) error
+}
+
+// DestroyOptions selects what destroy removes of a prepared project
+type DestroyOptions struct {
+	// Profile selects the local credentials profile the infrastructure is destroyed with
+	Profile string
+	// KeepInfrastructure leaves the cloud resources and the bucket holding their state in place
+	KeepInfrastructure bool
+	// KeepRepository leaves the GitHub repository in place, deleting only the environments prepare created
+	KeepRepository bool
+}
+
+// DestroySummary lists what destroy removed
+type DestroySummary struct {
+	// Infrastructure lists the infrastructure directories whose resources were destroyed
+	Infrastructure []string `json:"infrastructure,omitempty" yaml:"infrastructure,omitempty"`
+	Bucket         string   `json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	Environments   []string `json:"environments,omitempty" yaml:"environments,omitempty"`
+	Repository     string   `json:"repository,omitempty" yaml:"repository,omitempty"`
+}
+
+// Destroyer is implemented by providers that can tear down what prepare and deploy created
+type Destroyer interface {
+	DestroyWithOptions(ctx context.Context, opts DestroyOptions) (*DestroySummary, error)
 }
 
 type ProviderFactory interface {
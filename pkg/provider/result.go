@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+)
+
+// PrepareResult describes what a preparation created, for tools reading the --output of prepare
+type PrepareResult struct {
+	Provider      string                 `json:"provider" yaml:"provider"`
+	Repository    string                 `json:"repository,omitempty" yaml:"repository,omitempty"`
+	RepositoryURL string                 `json:"repositoryUrl,omitempty" yaml:"repositoryUrl,omitempty"`
+	Branch        string                 `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Template      TemplateRef            `json:"template" yaml:"template"`
+	Commits       []github.CreatedCommit `json:"commits" yaml:"commits"`
+	Secrets       []string               `json:"secrets" yaml:"secrets"`
+	Variables     []string               `json:"variables" yaml:"variables"`
+	Steps         []StepResult           `json:"steps" yaml:"steps"`
+	NextSteps     []string               `json:"nextSteps,omitempty" yaml:"nextSteps,omitempty"`
+}
+
+// TemplateRef identifies the template version a repository was rendered from
+type TemplateRef struct {
+	Source string `json:"source" yaml:"source"`
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+}
+
+// DeployResult describes a dispatched deployment, for tools reading the --output of deploy
+type DeployResult struct {
+	Provider   string       `json:"provider" yaml:"provider"`
+	Repository string       `json:"repository" yaml:"repository"`
+	Workflow   string       `json:"workflow" yaml:"workflow"`
+	Ref        string       `json:"ref" yaml:"ref"`
+	Run        *RunResult   `json:"run,omitempty" yaml:"run,omitempty"`
+	Steps      []StepResult `json:"steps" yaml:"steps"`
+}
+
+// RunResult is the outcome of a workflow run
+type RunResult struct {
+	ID         string `json:"id" yaml:"id"`
+	URL        string `json:"url" yaml:"url"`
+	Status     string `json:"status" yaml:"status"`
+	Conclusion string `json:"conclusion,omitempty" yaml:"conclusion,omitempty"`
+}
+
+// NewRunResult returns the result of run
+func NewRunResult(run *github.WorkflowRun) *RunResult {
+	if run == nil {
+		return nil
+	}
+	return &RunResult{ID: run.ID, URL: run.URL, Status: run.Status, Conclusion: run.Conclusion}
+}
+
+// StepResult is the outcome and duration of a step of a preparation or deployment
+type StepResult struct {
+	Name       string `json:"name" yaml:"name"`
+	Status     string `json:"status" yaml:"status"`
+	DurationMS int64  `json:"durationMs" yaml:"durationMs"`
+}
+
+// NewStepResult returns the result of a step that took d
+func NewStepResult(name string, status string, d time.Duration) StepResult {
+	return StepResult{Name: name, Status: status, DurationMS: d.Milliseconds()}
+}
+
+// Reporter is implemented by providers that describe the outcome of their last preparation or deployment.
+// The results are filled in as far as the operation got when it failed
+type Reporter interface {
+	PrepareResult() *PrepareResult
+	DeployResult() *DeployResult
+}
@@ -0,0 +1,91 @@
+package resources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+)
+
+// Backup keeps copies of files replaced during a single run under
+// .git/enterprise/backups/<time>-<run id>, together with an index listing them.
+// A file saved again keeps its first copy, the one from before the run.
+type Backup struct {
+	root    string
+	dir     string
+	entries []string
+	saved   map[string]bool
+}
+
+// NewBackup creates a backup for the repository at repoRoot. The backup
+// directory is only created once the first file is saved.
+func NewBackup(repoRoot string) (*Backup, error) {
+	absRoot, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for '%s': %w", repoRoot, err)
+	}
+
+	// The common git directory outlives temporary worktrees the preparation may run in
+	gitDir, err := github.GitCommonDir(absRoot)
+	if err != nil {
+		gitDir = filepath.Join(absRoot, ".git")
+	}
+
+	// The time keeps backups sorted, the run ID tells apart runs started in the same second
+	name := time.Now().Format("20060102-150405") + "-" + logging.GetLogger().RunID()
+	return &Backup{
+		root:  absRoot,
+		dir:   filepath.Join(gitDir, "enterprise", "backups", name),
+		saved: map[string]bool{},
+	}, nil
+}
+
+// Dir returns the directory holding the backed up files
+func (b *Backup) Dir() string {
+	return b.dir
+}
+
+// Entries returns the repository-relative paths of all backed up files
+func (b *Backup) Entries() []string {
+	return b.entries
+}
+
+// Save copies the file at path into the backup directory, keeping its path relative to the repository root
+func (b *Backup) Save(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for '%s': %w", path, err)
+	}
+	rel, err := filepath.Rel(b.root, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("cannot back up '%s', it is outside of the repository", path)
+	}
+	rel = filepath.ToSlash(rel)
+	if b.saved[rel] {
+		return nil
+	}
+
+	target := filepath.Join(b.dir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory '%s': %w", filepath.Dir(target), err)
+	}
+	if err := copyFile(absPath, target); err != nil {
+		return err
+	}
+
+	b.saved[rel] = true
+	b.entries = append(b.entries, rel)
+	return b.writeIndex()
+}
+
+func (b *Backup) writeIndex() error {
+	content := strings.Join(b.entries, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(b.dir, "index.txt"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write backup index: %w", err)
+	}
+	return nil
+}
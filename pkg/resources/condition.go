@@ -0,0 +1,223 @@
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// evaluateCondition evaluates a mapping "when" expression against the variables.
+// Supported syntax: comparisons (provider == "aws", region != "us-east-1"),
+// bare variables (true when set and not "false"), !, &&, || and parentheses.
+// Identifiers missing from variables are an error rather than an empty value.
+func evaluateCondition(expr string, variables map[string]string) (bool, error) {
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return false, err
+	}
+
+	p := &conditionParser{tokens: tokens, variables: variables}
+	result, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected %q in condition %q", p.tokens[p.pos].value, expr)
+	}
+	return result, nil
+}
+
+type conditionTokenKind int
+
+const (
+	tokenIdent conditionTokenKind = iota
+	tokenString
+	tokenOperator
+)
+
+type conditionToken struct {
+	kind  conditionTokenKind
+	value string
+}
+
+func tokenizeCondition(expr string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in condition %q", expr)
+			}
+			value := string(runes[i+1 : end])
+			if r == '"' {
+				unquoted, err := strconv.Unquote(string(runes[i : end+1]))
+				if err != nil {
+					return nil, fmt.Errorf("invalid string in condition %q: %w", expr, err)
+				}
+				value = unquoted
+			}
+			tokens = append(tokens, conditionToken{kind: tokenString, value: value})
+			i = end + 1
+		case strings.HasPrefix(string(runes[i:]), "=="),
+			strings.HasPrefix(string(runes[i:]), "!="),
+			strings.HasPrefix(string(runes[i:]), "&&"),
+			strings.HasPrefix(string(runes[i:]), "||"):
+			tokens = append(tokens, conditionToken{kind: tokenOperator, value: string(runes[i : i+2])})
+			i += 2
+		case r == '!' || r == '(' || r == ')':
+			tokens = append(tokens, conditionToken{kind: tokenOperator, value: string(r)})
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '-' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, conditionToken{kind: tokenIdent, value: string(runes[i:end])})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q in condition %q", r, expr)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+	return tokens, nil
+}
+
+type conditionParser struct {
+	tokens    []conditionToken
+	pos       int
+	variables map[string]string
+}
+
+func (p *conditionParser) peekOperator(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].value == op
+}
+
+func (p *conditionParser) parseOr() (bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for p.peekOperator("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		left = left || right
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (bool, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return false, err
+	}
+	for p.peekOperator("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return false, err
+		}
+		left = left && right
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (bool, error) {
+	if p.peekOperator("!") {
+		p.pos++
+		value, err := p.parseUnary()
+		return !value, err
+	}
+	if p.peekOperator("(") {
+		p.pos++
+		value, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if !p.peekOperator(")") {
+			return false, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return value, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return false, err
+	}
+
+	if p.peekOperator("==") || p.peekOperator("!=") {
+		op := p.tokens[p.pos].value
+		p.pos++
+		right, err := p.parseOperand()
+		if err != nil {
+			return false, err
+		}
+		if op == "==" {
+			return left.value == right.value, nil
+		}
+		return left.value != right.value, nil
+	}
+
+	if left.isString {
+		return false, fmt.Errorf("string %q is not a condition", left.value)
+	}
+	return left.value != "" && left.value != "false", nil
+}
+
+type conditionOperand struct {
+	value    string
+	isString bool
+}
+
+func (p *conditionParser) parseOperand() (conditionOperand, error) {
+	if p.pos >= len(p.tokens) {
+		return conditionOperand{}, fmt.Errorf("unexpected end of condition")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenString:
+		return conditionOperand{value: tok.value, isString: true}, nil
+	case tokenIdent:
+		switch tok.value {
+		case "true", "false":
+			return conditionOperand{value: tok.value}, nil
+		}
+		value, ok := p.variables[tok.value]
+		if !ok {
+			return conditionOperand{}, fmt.Errorf("unknown variable %q, expected one of: %s", tok.value, strings.Join(variableNames(p.variables), ", "))
+		}
+		return conditionOperand{value: value}, nil
+	}
+	return conditionOperand{}, fmt.Errorf("unexpected %q", tok.value)
+}
+
+func variableNames(variables map[string]string) []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,94 @@
+package resources
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/codemod"
+)
+
+// ConflictPolicy decides what happens when a mapping destination already exists with different contents
+type ConflictPolicy string
+
+const (
+	// ConflictPrompt asks through the conflict resolver, falling back to overwrite when none is set
+	ConflictPrompt    ConflictPolicy = "prompt"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictFail      ConflictPolicy = "fail"
+	ConflictMerge     ConflictPolicy = "merge"
+	// ConflictKeepBoth moves the existing file aside to <name>.orig before writing the new one
+	ConflictKeepBoth ConflictPolicy = "keep-both"
+)
+
+// ConflictPolicies lists all valid policies
+var ConflictPolicies = []ConflictPolicy{ConflictPrompt, ConflictOverwrite, ConflictSkip, ConflictFail, ConflictMerge, ConflictKeepBoth}
+
+// ConflictResolver chooses a policy for an existing destination file interactively.
+// canMerge reports whether ConflictMerge is supported for the file's format.
+type ConflictResolver func(dst string, mapping Mapping, canMerge bool) (ConflictPolicy, error)
+
+// lineMergeFiles are line-based formats merged by appending missing lines
+var lineMergeFiles = []string{".gitignore", ".dockerignore", ".prettierignore", ".eslintignore", ".npmrc"}
+
+// canMergeFile reports whether mergeContents supports the format of path
+func canMergeFile(path string) bool {
+	return mergeStrategy(path) != ""
+}
+
+func mergeStrategy(path string) string {
+	name := filepath.Base(path)
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		return "json"
+	}
+	if strings.HasPrefix(name, ".env") {
+		return "lines"
+	}
+	for _, lineFile := range lineMergeFiles {
+		if name == lineFile {
+			return "lines"
+		}
+	}
+	return ""
+}
+
+// mergeContents merges incoming into existing for known formats. Existing
+// values always win: JSON objects only gain missing keys and line-based
+// files only gain missing lines.
+func mergeContents(path string, existing, incoming []byte) ([]byte, error) {
+	switch mergeStrategy(path) {
+	case "json":
+		return codemod.MergeJson(existing, incoming)
+	case "lines":
+		return mergeLines(existing, incoming), nil
+	}
+	return nil, fmt.Errorf("merging is not supported for '%s'", filepath.Base(path))
+}
+
+func mergeLines(existing, incoming []byte) []byte {
+	present := map[string]bool{}
+	for _, line := range strings.Split(string(existing), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, line := range strings.Split(string(incoming), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || present[trimmed] {
+			continue
+		}
+		present[trimmed] = true
+		missing = append(missing, line)
+	}
+
+	if len(missing) == 0 {
+		return existing
+	}
+
+	merged := string(existing)
+	if merged != "" && !strings.HasSuffix(merged, "\n") {
+		merged += "\n"
+	}
+	return []byte(merged + strings.Join(missing, "\n") + "\n")
+}
@@ -0,0 +1,68 @@
+package resources
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// hasGlobMeta reports whether the path contains any glob metacharacters
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// globBase returns the longest leading directory of pattern that contains no glob metacharacters
+func globBase(pattern string) string {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	base := []string{}
+	for _, segment := range segments {
+		if hasGlobMeta(segment) {
+			break
+		}
+		base = append(base, segment)
+	}
+
+	dir := strings.Join(base, "/")
+	if dir == "" && strings.HasPrefix(pattern, "/") {
+		dir = "/"
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.FromSlash(dir)
+}
+
+// matchGlob matches path against pattern segment by segment, where a "**"
+// segment matches any number of directories, including none
+func matchGlob(pattern, path string) bool {
+	return matchSegments(
+		strings.Split(filepath.ToSlash(pattern), "/"),
+		strings.Split(filepath.ToSlash(path), "/"),
+	)
+}
+
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(path) == 0 {
+			return false
+		}
+		ok, err := filepath.Match(pattern[0], path[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		path = path[1:]
+	}
+	return len(path) == 0
+}
@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -8,24 +9,47 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 
+	"github.com/blazity/enterprise-cli/pkg/layout"
 	"github.com/blazity/enterprise-cli/pkg/logging"
-	"gopkg.in/yaml.v3"
 )
 
+// Mapping describes how a template resource is copied into the repository.
+// Source may be a file, a directory or a glob pattern (supporting "**"), Target
+// renames a single copied file or directory, Template renders file contents with
+// the prepare-time variables, When makes the mapping conditional and Conflict
+// decides what happens to destination files that already exist.
 type Mapping struct {
-	LegibleName string `yaml:"legible-name"`
-	Source      string `yaml:"source"`
-	Destination string `yaml:"destination"`
+	LegibleName string         `yaml:"legible-name"`
+	Source      string         `yaml:"source"`
+	Destination string         `yaml:"destination"`
+	Target      string         `yaml:"target"`
+	Template    bool           `yaml:"template"`
+	When        string         `yaml:"when"`
+	Conflict    ConflictPolicy `yaml:"conflict"`
 }
 
 type Config struct {
+	Version  int       `yaml:"version"`
 	Mappings []Mapping `yaml:"mappings"`
 }
 
 type ResourceManager struct {
-	config  *Config
-	rootDir string
+	config    *Config
+	rootDir   string
+	variables map[string]string
+	resolver  ConflictResolver
+	backup    *Backup
+	destRoot  string
+	rewrites  map[string]string
+}
+
+// sourceFile is a single file matched by a mapping source, with its path relative to the copied root
+type sourceFile struct {
+	path    string
+	relPath string
+	fromDir bool
 }
 
 func findUniqueMapYML(rootDir string) (string, string, error) {
@@ -64,7 +88,9 @@ func findUniqueMapYML(rootDir string) (string, string, error) {
 	return mapPath, configDir, nil
 }
 
-func NewResourceManager(rootDir string) (*ResourceManager, error) {
+// NewResourceManager loads the unique _map.yml found under rootDir. The variables
+// are available to conditions, destinations and templated file contents.
+func NewResourceManager(rootDir string, variables map[string]string) (*ResourceManager, error) {
 	logger := logging.GetLogger()
 	logger.Debug("Finding _map.yml file... in", "directory", rootDir)
 	mapPath, configDir, err := findUniqueMapYML(rootDir)
@@ -72,25 +98,18 @@ func NewResourceManager(rootDir string) (*ResourceManager, error) {
 		return nil, fmt.Errorf("failed to find _map.yml: %w", err)
 	}
 
-	f, err := os.Open(mapPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open config file '%s': %w", mapPath, err)
+	cfg, issues, err := loadConfig(mapPath, configDir, rootDir, variables)
+	for _, issue := range issues {
+		if issue.Severity == SeverityWarning {
+			logger.Debug("Template _map.yml warning", "issue", issue.String())
+		}
 	}
-	defer f.Close()
-
-	var cfg Config
-	decoder := yaml.NewDecoder(f)
-
-	if err := decoder.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to decode yaml from '%s': %w", mapPath, err)
+	if err != nil {
+		return nil, err
 	}
 
 	for i := range cfg.Mappings {
-		if !filepath.IsAbs(cfg.Mappings[i].Source) {
-			cfg.Mappings[i].Source = filepath.Join(configDir, cfg.Mappings[i].Source)
-		}
-
-		cfg.Mappings[i].Source = filepath.Clean(cfg.Mappings[i].Source)
+		cfg.Mappings[i].Source = filepath.Clean(filepath.Join(configDir, cfg.Mappings[i].Source))
 	}
 
 	absRootDir, err := filepath.Abs(rootDir)
@@ -98,20 +117,32 @@ func NewResourceManager(rootDir string) (*ResourceManager, error) {
 		return nil, fmt.Errorf("failed to get absolute path for root directory '%s': %w", rootDir, err)
 	}
 
+	if variables == nil {
+		variables = map[string]string{}
+	}
+
 	rm := &ResourceManager{
-		config:  &cfg,
-		rootDir: absRootDir,
+		config:    cfg,
+		rootDir:   absRootDir,
+		variables: variables,
 	}
 
 	return rm, nil
 }
 
-// normalizeDestination processes destination paths by removing special variables
-// and handling empty paths. It returns the absolute path to the destination directory.
+// normalizeDestination processes destination paths by removing special variables,
+// expanding ${name} variables and handling empty paths. It returns the absolute path
+// to the destination directory.
 func (rm *ResourceManager) normalizeDestination(destination string) (string, error) {
 	dest := destination
+	inApp := strings.HasPrefix(dest, "${next-enterprise}")
 	dest = strings.Replace(dest, "${next-enterprise}/", "", 1)
 	dest = strings.Replace(dest, "${next-enterprise}", "", 1)
+	dest = rm.expandVariables(dest)
+	if !inApp {
+		// Application paths follow the application when it is moved, the others are relocated here
+		dest = layout.RewritePath(dest, rm.rewrites)
+	}
 
 	logging.GetLogger().Debug("Normalized destination path", "path", dest)
 
@@ -119,45 +150,281 @@ func (rm *ResourceManager) normalizeDestination(destination string) (string, err
 		dest = "."
 	}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("failed to get current working directory: %w", err)
+	root := rm.destRoot
+	if root == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current working directory: %w", err)
+		}
+		root = cwd
 	}
 
-	return filepath.Join(cwd, dest), nil
+	destDir := filepath.Join(root, dest)
+	if !isWithin(root, destDir) {
+		return "", fmt.Errorf("destination '%s' escapes the repository", destination)
+	}
+
+	return destDir, nil
 }
 
-func (rm *ResourceManager) copyMapping(mapping Mapping) error {
+// expandVariables replaces ${name} occurrences with the matching variable value
+func (rm *ResourceManager) expandVariables(s string) string {
+	for name, value := range rm.variables {
+		s = strings.ReplaceAll(s, "${"+name+"}", value)
+	}
+	return s
+}
 
+// expandSource resolves a mapping source into the list of files it refers to
+func expandSource(mapping Mapping) ([]sourceFile, error) {
 	src := mapping.Source
+
+	if hasGlobMeta(src) {
+		base := globBase(src)
+		var files []sourceFile
+		err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !matchGlob(src, path) {
+				return nil
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, sourceFile{path: path, relPath: rel})
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to expand source pattern '%s': %w", src, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("source pattern '%s' for mapping '%s' did not match any files", src, mapping.LegibleName)
+		}
+		if mapping.Target != "" && len(files) > 1 {
+			return nil, fmt.Errorf("target '%s' for mapping '%s' requires a single source file, but the pattern matched %d", mapping.Target, mapping.LegibleName, len(files))
+		}
+		return files, nil
+	}
+
 	info, err := os.Stat(src)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("source file '%s' for mapping '%s' does not exist", src, mapping.LegibleName)
+			return nil, fmt.Errorf("source file '%s' for mapping '%s' does not exist", src, mapping.LegibleName)
 		}
-		return fmt.Errorf("failed to stat source file '%s': %w", src, err)
+		return nil, fmt.Errorf("failed to stat source file '%s': %w", src, err)
 	}
-	if info.IsDir() {
-		return fmt.Errorf("source path '%s' for mapping '%s' is a directory", src, mapping.LegibleName)
+
+	if !info.IsDir() {
+		return []sourceFile{{path: src, relPath: filepath.Base(src)}}, nil
 	}
 
-	dest := mapping.Destination
+	var files []sourceFile
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, sourceFile{path: path, relPath: filepath.Join(filepath.Base(src), rel), fromDir: true})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk source directory '%s': %w", src, err)
+	}
+	return files, nil
+}
 
-	destDir, err := rm.normalizeDestination(dest)
+// targetPath returns the destination of a source file, applying the mapping's target name
+func (rm *ResourceManager) targetPath(destDir string, mapping Mapping, file sourceFile) string {
+	rel := file.relPath
+	if mapping.Target != "" {
+		target := rm.expandVariables(mapping.Target)
+		if file.fromDir {
+			parts := strings.SplitN(rel, string(filepath.Separator), 2)
+			parts[0] = target
+			rel = filepath.Join(parts...)
+		} else {
+			rel = filepath.Join(filepath.Dir(rel), target)
+		}
+	}
+	return filepath.Join(destDir, rel)
+}
+
+// SetConflictResolver sets the function asked to pick a policy for mappings using ConflictPrompt
+func (rm *ResourceManager) SetConflictResolver(resolver ConflictResolver) {
+	rm.resolver = resolver
+}
+
+// SetDestinationRoot sets the repository root destinations are resolved against, defaulting to the working directory
+func (rm *ResourceManager) SetDestinationRoot(dir string) {
+	rm.destRoot = dir
+}
+
+// SetPathRewrites relocates destinations outside the application, mapping template directories to their configured locations
+func (rm *ResourceManager) SetPathRewrites(rewrites map[string]string) {
+	rm.rewrites = rewrites
+}
+
+// SetBackup sets where replaced destination files are saved before being changed
+func (rm *ResourceManager) SetBackup(backup *Backup) {
+	rm.backup = backup
+}
+
+// CopyMapping copies a mapping that is not part of the _map.yml, such as a directory every template ships,
+// resolving existing destination files like the configured mappings
+func (rm *ResourceManager) CopyMapping(mapping Mapping) ([]string, error) {
+	copied, err := rm.copyMapping(mapping)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed processing mapping '%s': %w", mapping.LegibleName, err)
 	}
+	return copied, nil
+}
 
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return fmt.Errorf("failed to create destination directory '%s': %w", destDir, err)
+func (rm *ResourceManager) copyMapping(mapping Mapping) ([]string, error) {
+	files, err := expandSource(mapping)
+	if err != nil {
+		return nil, err
 	}
 
-	dst := filepath.Join(destDir, filepath.Base(src))
-	if err := copyFile(src, dst); err != nil {
-		return err
+	destDir, err := rm.normalizeDestination(mapping.Destination)
+	if err != nil {
+		return nil, err
 	}
 
-	logging.GetLogger().Debug("Copied mapping", "from", src, "to", dst)
+	copied := make([]string, 0, len(files))
+	for _, file := range files {
+		dst := rm.targetPath(destDir, mapping, file)
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create destination directory '%s': %w", filepath.Dir(dst), err)
+		}
+
+		var content []byte
+		if mapping.Template {
+			content, err = rm.renderFile(file.path)
+		} else {
+			content, err = os.ReadFile(file.path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		written, err := rm.writeResource(dst, content, mapping)
+		if err != nil {
+			return nil, err
+		}
+
+		logging.GetLogger().Debug("Copied mapping", "from", file.path, "to", dst)
+		copied = append(copied, written...)
+	}
+
+	return copied, nil
+}
+
+// writeResource writes content to dst, applying the mapping's conflict policy when
+// dst already exists with different contents. It returns the paths that changed.
+func (rm *ResourceManager) writeResource(dst string, content []byte, mapping Mapping) ([]string, error) {
+	logger := logging.GetLogger()
+
+	existing, err := os.ReadFile(dst)
+	if os.IsNotExist(err) {
+		return []string{dst}, writeFile(dst, content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read existing file '%s': %w", dst, err)
+	}
+	if bytes.Equal(existing, content) {
+		return []string{dst}, nil
+	}
+
+	policy := mapping.Conflict
+	if policy == "" || policy == ConflictPrompt {
+		policy = ConflictOverwrite
+		if rm.resolver != nil {
+			policy, err = rm.resolver(dst, mapping, canMergeFile(dst))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch policy {
+	case ConflictSkip:
+		logger.Info("Kept existing file", "path", dst)
+		return nil, nil
+	case ConflictFail:
+		return nil, fmt.Errorf("destination file '%s' already exists", dst)
+	case ConflictMerge:
+		merged, err := mergeContents(dst, existing, content)
+		if err != nil {
+			return nil, err
+		}
+		if err := rm.backupFile(dst); err != nil {
+			return nil, err
+		}
+		logger.Info("Merged into existing file", "path", dst)
+		return []string{dst}, writeFile(dst, merged)
+	case ConflictKeepBoth:
+		if err := rm.backupFile(dst); err != nil {
+			return nil, err
+		}
+		orig := dst + ".orig"
+		if err := os.Rename(dst, orig); err != nil {
+			return nil, fmt.Errorf("failed to move existing file '%s' aside: %w", dst, err)
+		}
+		logger.Info("Kept existing file alongside the new one", "path", orig)
+		return []string{dst, orig}, writeFile(dst, content)
+	case ConflictOverwrite:
+		if err := rm.backupFile(dst); err != nil {
+			return nil, err
+		}
+		logger.Debug("Overwriting existing file", "path", dst)
+		return []string{dst}, writeFile(dst, content)
+	}
+
+	return nil, fmt.Errorf("unknown conflict policy '%s' for mapping '%s'", policy, mapping.LegibleName)
+}
+
+func (rm *ResourceManager) backupFile(path string) error {
+	if rm.backup == nil {
+		return nil
+	}
+	if err := rm.backup.Save(path); err != nil {
+		return fmt.Errorf("failed to back up '%s': %w", path, err)
+	}
+	return nil
+}
+
+// renderFile executes src as a Go template with the manager variables
+func (rm *ResourceManager) renderFile(src string) ([]byte, error) {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file '%s': %w", src, err)
+	}
+
+	tmpl, err := template.New(filepath.Base(src)).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template file '%s': %w", src, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, rm.variables); err != nil {
+		return nil, fmt.Errorf("failed to render template file '%s': %w", src, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeFile(dst string, content []byte) error {
+	if err := os.WriteFile(dst, content, 0644); err != nil {
+		return fmt.Errorf("failed to write destination file '%s': %w", dst, err)
+	}
 	return nil
 }
 
@@ -190,17 +457,23 @@ func (rm *ResourceManager) CopyAllMappings() ([]string, error) {
 	destinationPaths := []string{}
 
 	for _, mapping := range rm.config.Mappings {
-		if err := rm.copyMapping(mapping); err != nil {
-			return nil, fmt.Errorf("failed processing mapping '%s': %w", mapping.LegibleName, err)
+		if mapping.When != "" {
+			ok, err := evaluateCondition(mapping.When, rm.variables)
+			if err != nil {
+				return nil, fmt.Errorf("invalid condition for mapping '%s': %w", mapping.LegibleName, err)
+			}
+			if !ok {
+				logging.GetLogger().Debug("Skipping mapping, condition not met", "mapping", mapping.LegibleName, "when", mapping.When)
+				continue
+			}
 		}
 
-		destDir, err := rm.normalizeDestination(mapping.Destination)
+		copied, err := rm.copyMapping(mapping)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed processing mapping '%s': %w", mapping.LegibleName, err)
 		}
 
-		destPath := filepath.Join(destDir, filepath.Base(mapping.Source))
-		destinationPaths = append(destinationPaths, destPath)
+		destinationPaths = append(destinationPaths, copied...)
 	}
 
 	return destinationPaths, nil
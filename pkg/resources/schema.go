@@ -0,0 +1,367 @@
+package resources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the _map.yml schema version understood by this CLI
+const SchemaVersion = 1
+
+// Severity of a validation issue
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a single validation finding in a _map.yml file
+type Issue struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column, i.Severity, i.Message)
+}
+
+// ValidationError is returned when a _map.yml file contains errors
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		if issue.Severity == SeverityError {
+			lines = append(lines, issue.String())
+		}
+	}
+	return fmt.Sprintf("invalid _map.yml:\n  %s", strings.Join(lines, "\n  "))
+}
+
+var (
+	configKeys  = []string{"version", "mappings"}
+	mappingKeys = []string{"legible-name", "source", "destination", "target", "template", "when", "conflict"}
+)
+
+var variablePattern = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// Lint validates the unique _map.yml found under dir and returns every issue found. Conditions may only
+// refer to the given variable names
+func Lint(dir string, variables []string) ([]Issue, error) {
+	mapPath, configDir, err := findUniqueMapYML(dir)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]string, len(variables))
+	for _, name := range variables {
+		known[name] = ""
+	}
+	_, issues, err := loadConfig(mapPath, configDir, dir, known)
+	return issues, err
+}
+
+// HasErrors reports whether any of the issues is an error
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfig strictly decodes and validates mapPath. Source paths are resolved against configDir
+// and must stay within rootDir, and conditions may only refer to variables. The config is nil when
+// validation reports errors.
+func loadConfig(mapPath, configDir, rootDir string, variables map[string]string) (*Config, []Issue, error) {
+	src, err := os.ReadFile(mapPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open config file '%s': %w", mapPath, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode yaml from '%s': %w", mapPath, err)
+	}
+
+	absRootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get absolute path for root directory '%s': %w", rootDir, err)
+	}
+
+	v := &validator{file: mapPath, configDir: configDir, rootDir: absRootDir, variables: variables}
+	v.validateDocument(&doc)
+	sort.SliceStable(v.issues, func(i, j int) bool { return v.issues[i].Line < v.issues[j].Line })
+
+	if HasErrors(v.issues) {
+		return nil, v.issues, &ValidationError{Issues: v.issues}
+	}
+
+	var cfg Config
+	if len(doc.Content) > 0 {
+		if err := doc.Content[0].Decode(&cfg); err != nil {
+			return nil, v.issues, fmt.Errorf("failed to decode yaml from '%s': %w", mapPath, err)
+		}
+	}
+	return &cfg, v.issues, nil
+}
+
+type validator struct {
+	file      string
+	configDir string
+	rootDir   string
+	variables map[string]string
+	issues    []Issue
+}
+
+func (v *validator) report(node *yaml.Node, severity Severity, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{
+		File:     v.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validateDocument(doc *yaml.Node) {
+	if len(doc.Content) == 0 {
+		v.issues = append(v.issues, Issue{File: v.file, Line: 1, Column: 1, Severity: SeverityError, Message: "file is empty"})
+		return
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		v.report(root, SeverityError, "expected a mapping with 'version' and 'mappings' keys")
+		return
+	}
+
+	fields := v.fields(root, configKeys)
+
+	if version, ok := fields["version"]; ok {
+		var n int
+		if err := version.Decode(&n); err != nil {
+			v.report(version, SeverityError, "version must be an integer")
+		} else if n != SchemaVersion {
+			v.report(version, SeverityError, "unsupported schema version %d, this CLI supports version %d", n, SchemaVersion)
+		}
+	} else {
+		v.report(root, SeverityWarning, "missing 'version', assuming version %d", SchemaVersion)
+	}
+
+	mappings, ok := fields["mappings"]
+	if !ok {
+		v.report(root, SeverityError, "missing required key 'mappings'")
+		return
+	}
+	if mappings.Kind != yaml.SequenceNode {
+		v.report(mappings, SeverityError, "'mappings' must be a list")
+		return
+	}
+
+	names := map[string]int{}
+	for _, mapping := range mappings.Content {
+		v.validateMapping(mapping, names)
+	}
+}
+
+// fields returns the values of a mapping node by key, reporting unknown and duplicate keys
+func (v *validator) fields(node *yaml.Node, known []string) map[string]*yaml.Node {
+	fields := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if !containsString(known, key.Value) {
+			msg := fmt.Sprintf("unknown key '%s'", key.Value)
+			if suggestion := closestMatch(key.Value, known); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean '%s'?", suggestion)
+			}
+			v.report(key, SeverityError, "%s", msg)
+			continue
+		}
+		if _, dup := fields[key.Value]; dup {
+			v.report(key, SeverityError, "duplicate key '%s'", key.Value)
+			continue
+		}
+		fields[key.Value] = value
+	}
+	return fields
+}
+
+func (v *validator) validateMapping(node *yaml.Node, names map[string]int) {
+	if node.Kind != yaml.MappingNode {
+		v.report(node, SeverityError, "each mapping must be a mapping of keys")
+		return
+	}
+
+	fields := v.fields(node, mappingKeys)
+	str := func(key string) (string, *yaml.Node) {
+		value, ok := fields[key]
+		if !ok {
+			return "", nil
+		}
+		if value.Kind != yaml.ScalarNode || value.Tag == "!!null" {
+			v.report(value, SeverityError, "'%s' must be a string", key)
+			return "", nil
+		}
+		return value.Value, value
+	}
+
+	name, nameNode := str("legible-name")
+	if nameNode == nil || strings.TrimSpace(name) == "" {
+		v.report(node, SeverityError, "mapping is missing 'legible-name'")
+	} else if line, dup := names[name]; dup {
+		v.report(nameNode, SeverityWarning, "legible-name '%s' is already used on line %d", name, line)
+	} else {
+		names[name] = nameNode.Line
+	}
+
+	source, sourceNode := str("source")
+	if sourceNode == nil || strings.TrimSpace(source) == "" {
+		v.report(node, SeverityError, "mapping is missing 'source'")
+	} else {
+		v.validateSource(sourceNode, source)
+	}
+
+	destination, destinationNode := str("destination")
+	if destinationNode == nil {
+		v.report(node, SeverityWarning, "mapping has no 'destination', files will be copied to the repository root")
+	} else {
+		v.validateDestination(destinationNode, destination)
+	}
+
+	if target, targetNode := str("target"); targetNode != nil {
+		if target == "" || target == "." || target == ".." || strings.ContainsAny(target, `/\`) {
+			v.report(targetNode, SeverityError, "target must be a plain file or directory name")
+		}
+	}
+
+	if template, ok := fields["template"]; ok {
+		var b bool
+		if err := template.Decode(&b); err != nil {
+			v.report(template, SeverityError, "'template' must be true or false")
+		}
+	}
+
+	if when, whenNode := str("when"); whenNode != nil {
+		if _, err := evaluateCondition(when, v.variables); err != nil {
+			v.report(whenNode, SeverityError, "invalid condition: %s", err)
+		}
+	}
+
+	if conflict, conflictNode := str("conflict"); conflictNode != nil {
+		valid := false
+		names := make([]string, 0, len(ConflictPolicies))
+		for _, policy := range ConflictPolicies {
+			names = append(names, string(policy))
+			if ConflictPolicy(conflict) == policy {
+				valid = true
+			}
+		}
+		if !valid {
+			v.report(conflictNode, SeverityError, "unknown conflict policy '%s', expected one of: %s", conflict, strings.Join(names, ", "))
+		}
+	}
+}
+
+func (v *validator) validateSource(node *yaml.Node, source string) {
+	if filepath.IsAbs(source) {
+		v.report(node, SeverityError, "source '%s' must be relative to the _map.yml directory", source)
+		return
+	}
+
+	resolved := filepath.Clean(filepath.Join(v.configDir, source))
+	if !isWithin(v.rootDir, resolved) {
+		v.report(node, SeverityError, "source '%s' escapes the template directory", source)
+		return
+	}
+
+	if hasGlobMeta(source) {
+		return
+	}
+	if _, err := os.Stat(resolved); err != nil {
+		v.report(node, SeverityError, "source '%s' does not exist", source)
+	}
+}
+
+func (v *validator) validateDestination(node *yaml.Node, destination string) {
+	dest := strings.Replace(destination, "${next-enterprise}", "", 1)
+	dest = strings.TrimPrefix(dest, "/")
+	dest = variablePattern.ReplaceAllString(dest, "x")
+
+	if filepath.IsAbs(destination) {
+		v.report(node, SeverityError, "destination '%s' must be relative to the repository root", destination)
+		return
+	}
+	if dest != "" && !isWithin("/repo", filepath.Join("/repo", dest)) {
+		v.report(node, SeverityError, "destination '%s' escapes the repository", destination)
+	}
+}
+
+// isWithin reports whether path is root or lies below it
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closestMatch returns the candidate within edit distance 3 of s, if any
+func closestMatch(s string, candidates []string) string {
+	type scored struct {
+		name     string
+		distance int
+	}
+	var matches []scored
+	for _, candidate := range candidates {
+		if d := editDistance(s, candidate); d <= 3 {
+			matches = append(matches, scored{candidate, d})
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+	return matches[0].name
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
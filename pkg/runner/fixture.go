@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTPCommand is the command name of recorded HTTP requests. Their args are the method and the request
+// path, stdin holds the request body, stdout the response body and exit the status code
+const HTTPCommand = "http"
+
+// Interaction is a recorded command with its output
+type Interaction struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+	Stdin   string   `yaml:"stdin,omitempty"`
+	Stdout  string   `yaml:"stdout,omitempty"`
+	Stderr  string   `yaml:"stderr,omitempty"`
+	Exit    int      `yaml:"exit"`
+}
+
+// Fixture is a recording of the commands and HTTP requests of a run
+type Fixture struct {
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// LoadFixture reads a fixture written by a Recorder
+func LoadFixture(path string) (*Fixture, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var f Fixture
+	if err := yaml.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture to path
+func (f *Fixture) Save(path string) error {
+	content, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+var (
+	tempPathPattern  = regexp.MustCompile(regexp.QuoteMeta(filepath.Clean(os.TempDir())) + `/([^/\s]*?)[0-9]+`)
+	timestampPattern = regexp.MustCompile(`-[0-9]{10}\b`)
+)
+
+// key identifies an interaction for replay. Temporary directories and the Unix timestamps of branch names
+// differ between runs, so they are masked
+func key(command string, args []string) string {
+	line := strings.TrimSpace(command + " " + strings.Join(args, " "))
+	line = tempPathPattern.ReplaceAllString(line, "$$TMPDIR/${1}*")
+	return timestampPattern.ReplaceAllString(line, "-<timestamp>")
+}
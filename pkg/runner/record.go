@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Recorder runs commands with another runner and records them to a fixture file, which is rewritten after
+// every interaction so a failed or cancelled run is recorded up to the point it stopped
+type Recorder struct {
+	next Runner
+	path string
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder records the commands run by next to the fixture at path
+func NewRecorder(next Runner, path string) *Recorder {
+	return &Recorder{next: next, path: path}
+}
+
+func (r *Recorder) Run(ctx context.Context, cmd Command) (*Result, error) {
+	result, err := r.next.Run(ctx, cmd)
+
+	var exitErr *ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		// Missing executables and cancellations are not part of the recorded behaviour
+		return result, err
+	}
+
+	recordErr := r.record(Interaction{
+		Command: cmd.Name,
+		Args:    cmd.Args,
+		Stdin:   string(cmd.Stdin),
+		Stdout:  string(result.Stdout),
+		Stderr:  string(result.Stderr),
+		Exit:    result.ExitCode,
+	})
+	if err == nil {
+		err = recordErr
+	}
+	return result, err
+}
+
+func (r *Recorder) record(interaction Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fixture.Interactions = append(r.fixture.Interactions, interaction)
+	return r.fixture.Save(r.path)
+}
+
+func (r *Recorder) Transport(base http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{recorder: r, base: r.next.Transport(base)}
+}
+
+func (r *Recorder) Offline() bool {
+	return r.next.Offline()
+}
+
+type recordingTransport struct {
+	recorder *Recorder
+	base     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(content))
+
+	if err := t.recorder.record(Interaction{
+		Command: HTTPCommand,
+		Args:    []string{req.Method, req.URL.RequestURI()},
+		Stdin:   string(body),
+		Stdout:  string(content),
+		Exit:    resp.StatusCode,
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
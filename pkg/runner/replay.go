@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Replayer answers commands and HTTP requests from a fixture instead of running them. Each recorded
+// interaction is used once, and identical commands are answered in the order they were recorded,
+// so steps skipped in a replayed run do not shift the answers of later ones
+type Replayer struct {
+	passthrough map[string]bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer replays f. Commands named in passthrough, such as git for a simulation that still
+// changes the local repository, are run for real
+func NewReplayer(f *Fixture, passthrough ...string) *Replayer {
+	r := &Replayer{
+		passthrough:  map[string]bool{},
+		interactions: f.Interactions,
+		used:         make([]bool, len(f.Interactions)),
+	}
+	for _, name := range passthrough {
+		r.passthrough[name] = true
+	}
+	return r
+}
+
+func (r *Replayer) Run(ctx context.Context, cmd Command) (*Result, error) {
+	if r.passthrough[cmd.Name] {
+		return Exec{}.Run(ctx, cmd)
+	}
+	if err := ctx.Err(); err != nil {
+		return &Result{}, err
+	}
+
+	interaction, ok := r.next(cmd.Name, cmd.Args)
+	if !ok {
+		return &Result{}, fmt.Errorf("no recorded interaction for %s", cmd)
+	}
+
+	result := &Result{
+		Stdout:   []byte(interaction.Stdout),
+		Stderr:   []byte(interaction.Stderr),
+		ExitCode: interaction.Exit,
+	}
+	if result.ExitCode != 0 {
+		return result, &ExitError{Command: cmd.String(), ExitCode: result.ExitCode}
+	}
+	return result, nil
+}
+
+// next returns the first unused interaction matching command and args
+func (r *Replayer) next(command string, args []string) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	want := key(command, args)
+	for i, interaction := range r.interactions {
+		if !r.used[i] && key(interaction.Command, interaction.Args) == want {
+			r.used[i] = true
+			return interaction, true
+		}
+	}
+	return Interaction{}, false
+}
+
+// Remaining returns the interactions that were not replayed
+func (r *Replayer) Remaining() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var remaining []Interaction
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			remaining = append(remaining, interaction)
+		}
+	}
+	return remaining
+}
+
+func (r *Replayer) Transport(http.RoundTripper) http.RoundTripper {
+	return replayTransport{r}
+}
+
+func (r *Replayer) Offline() bool {
+	return true
+}
+
+type replayTransport struct {
+	replayer *Replayer
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	interaction, ok := t.replayer.next(HTTPCommand, []string{req.Method, req.URL.RequestURI()})
+	if !ok {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.RequestURI())
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Exit, http.StatusText(interaction.Exit)),
+		StatusCode:    interaction.Exit,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:          io.NopCloser(bytes.NewReader([]byte(interaction.Stdout))),
+		ContentLength: int64(len(interaction.Stdout)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// waitDelay bounds how long a killed command may keep its output pipes open, e.g. through an ssh
+// process spawned by git push, before its result is returned anyway
+const waitDelay = 2 * time.Second
+
+// Command is an external program invocation, such as git or gh
+type Command struct {
+	Name  string
+	Args  []string
+	Dir   string
+	Env   []string
+	Stdin []byte
+}
+
+// String returns the command line of c
+func (c Command) String() string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " "))
+}
+
+// Result is the output of a finished command
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// Combined returns stdout followed by stderr
+func (r *Result) Combined() []byte {
+	return append(append([]byte{}, r.Stdout...), r.Stderr...)
+}
+
+// ExitError is returned when a command exits with a non-zero status
+type ExitError struct {
+	Command  string
+	ExitCode int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// Runner runs external commands and carries the HTTP traffic of the GitHub API clients, so both can be
+// recorded and replayed
+type Runner interface {
+	// Run runs cmd, returning its output, which is never nil, and an *ExitError when it exits with a
+	// non-zero status. A missing executable is reported with an error wrapping exec.ErrNotFound
+	Run(ctx context.Context, cmd Command) (*Result, error)
+	// Transport wraps the transport of HTTP clients
+	Transport(base http.RoundTripper) http.RoundTripper
+	// Offline reports whether requests are answered without contacting the network, so no credentials are needed
+	Offline() bool
+}
+
+var (
+	current Runner = Exec{}
+	mu      sync.RWMutex
+)
+
+// Get returns the runner in use, which runs commands directly unless Set was called
+func Get() Runner {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Set replaces the runner used by every package
+func Set(r Runner) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = r
+}
+
+// Run runs cmd with the runner in use
+func Run(ctx context.Context, cmd Command) (*Result, error) {
+	return Get().Run(ctx, cmd)
+}
+
+// Exec runs commands as child processes, killing them when the context is done
+type Exec struct{}
+
+func (Exec) Run(ctx context.Context, cmd Command) (*Result, error) {
+	var stdout, stderr bytes.Buffer
+
+	c := exec.CommandContext(ctx, cmd.Name, cmd.Args...)
+	c.Dir = cmd.Dir
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	c.WaitDelay = waitDelay
+	if len(cmd.Env) > 0 {
+		c.Env = append(os.Environ(), cmd.Env...)
+	}
+	if cmd.Stdin != nil {
+		c.Stdin = bytes.NewReader(cmd.Stdin)
+	}
+
+	err := c.Run()
+	result := &Result{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		result.ExitCode = exitErr.ExitCode()
+		return result, &ExitError{Command: cmd.String(), ExitCode: result.ExitCode}
+	}
+	if err != nil && ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	return result, err
+}
+
+func (Exec) Transport(base http.RoundTripper) http.RoundTripper {
+	return base
+}
+
+func (Exec) Offline() bool {
+	return false
+}
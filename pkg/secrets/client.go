@@ -0,0 +1,264 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/cli/go-gh/pkg/api"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultConcurrency is how many values are stored at once
+const DefaultConcurrency = 4
+
+// Client stores secrets and variables through the GitHub REST API, so values never appear
+// in process arguments
+type Client struct {
+	rest api.RESTClient
+
+	mu      sync.Mutex
+	keys    map[string]PublicKey
+	repoIDs map[string]int64
+}
+
+// NewClient returns a client authenticated like the gh CLI
+func NewClient() (*Client, error) {
+	rest, err := github.RESTClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub API client: %w", err)
+	}
+	return &Client{
+		rest:    rest,
+		keys:    map[string]PublicKey{},
+		repoIDs: map[string]int64{},
+	}, nil
+}
+
+// Store sets every entry in target, concurrency at a time, and reports the outcome of each one.
+// A failing entry does not stop the others
+func (c *Client) Store(ctx context.Context, target Target, entries []Entry, concurrency int) *Report {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	report := &Report{Target: target, Results: make([]Result, len(entries))}
+
+	for _, entry := range entries {
+		if entry.Kind == KindSecret {
+			logging.RegisterSecret(entry.Value)
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, entry := range entries {
+		i, entry := i, entry
+		g.Go(func() error {
+			var err error
+			switch entry.Kind {
+			case KindSecret:
+				err = c.setSecret(ctx, target, entry)
+			case KindVariable:
+				err = c.setVariable(ctx, target, entry)
+			default:
+				err = fmt.Errorf("unknown kind %q", entry.Kind)
+			}
+			if err == nil {
+				logging.GetLogger().Debug("Stored value", "kind", entry.Kind, "name", entry.Name, "target", target)
+			}
+			report.Results[i] = Result{Name: entry.Name, Kind: entry.Kind, Err: err}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return report
+}
+
+func (c *Client) setSecret(ctx context.Context, target Target, entry Entry) error {
+	base := basePath(target, KindSecret)
+
+	key, err := c.publicKey(ctx, target)
+	if err != nil {
+		return err
+	}
+	encrypted, err := Encrypt(key, entry.Value)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"encrypted_value": encrypted,
+		"key_id":          key.KeyID,
+	}
+	if target.Scope == ScopeOrganization {
+		if err := c.shareWithRepository(ctx, target, KindSecret, entry.Name, body); err != nil {
+			return err
+		}
+	}
+
+	return c.send(ctx, http.MethodPut, base+"/"+url.PathEscape(entry.Name), body, nil)
+}
+
+func (c *Client) setVariable(ctx context.Context, target Target, entry Entry) error {
+	base := basePath(target, KindVariable)
+
+	body := map[string]interface{}{
+		"name":  entry.Name,
+		"value": entry.Value,
+	}
+	if target.Scope == ScopeOrganization {
+		if err := c.shareWithRepository(ctx, target, KindVariable, entry.Name, body); err != nil {
+			return err
+		}
+	}
+
+	err := c.send(ctx, http.MethodPatch, base+"/"+url.PathEscape(entry.Name), body, nil)
+	if isStatus(err, http.StatusNotFound) {
+		return c.send(ctx, http.MethodPost, base, body, nil)
+	}
+	return err
+}
+
+// shareWithRepository adds the visibility of an organization value to body, keeping the repositories
+// an existing value is already shared with and adding the target repository to them
+func (c *Client) shareWithRepository(ctx context.Context, target Target, kind Kind, name string, body map[string]interface{}) error {
+	base := basePath(target, kind) + "/" + url.PathEscape(name)
+
+	var existing struct {
+		Visibility string `json:"visibility"`
+	}
+	err := c.send(ctx, http.MethodGet, base, nil, &existing)
+	if err != nil && !isStatus(err, http.StatusNotFound) {
+		return err
+	}
+	if existing.Visibility != "" && existing.Visibility != "selected" {
+		body["visibility"] = existing.Visibility
+		return nil
+	}
+
+	repoID, err := c.repositoryID(ctx, target.Repository)
+	if err != nil {
+		return err
+	}
+	ids := []int64{repoID}
+
+	if existing.Visibility == "selected" {
+		var shared struct {
+			Repositories []struct {
+				ID int64 `json:"id"`
+			} `json:"repositories"`
+		}
+		if err := c.send(ctx, http.MethodGet, base+"/repositories?per_page=100", nil, &shared); err != nil {
+			return err
+		}
+		for _, repo := range shared.Repositories {
+			if repo.ID != repoID {
+				ids = append(ids, repo.ID)
+			}
+		}
+	}
+
+	body["visibility"] = "selected"
+	body["selected_repository_ids"] = ids
+	return nil
+}
+
+// publicKey returns the cached public key of target, fetching it once
+func (c *Client) publicKey(ctx context.Context, target Target) (PublicKey, error) {
+	path := basePath(target, KindSecret) + "/public-key"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[path]; ok {
+		return key, nil
+	}
+
+	var key PublicKey
+	if err := c.send(ctx, http.MethodGet, path, nil, &key); err != nil {
+		return PublicKey{}, fmt.Errorf("failed to get the public key of %s: %w", target, err)
+	}
+	c.keys[path] = key
+	return key, nil
+}
+
+func (c *Client) repositoryID(ctx context.Context, repository string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if id, ok := c.repoIDs[repository]; ok {
+		return id, nil
+	}
+
+	var repo struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.send(ctx, http.MethodGet, "repos/"+repository, nil, &repo); err != nil {
+		return 0, fmt.Errorf("failed to look up repository %s: %w", repository, err)
+	}
+	c.repoIDs[repository] = repo.ID
+	return repo.ID, nil
+}
+
+// send performs a request, decoding the response into response when given. Unlike RESTClient.Do
+// it accepts the empty bodies GitHub answers secret and variable writes with
+func (c *Client) send(ctx context.Context, method string, path string, body interface{}, response interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	resp, err := c.rest.RequestWithContext(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if response == nil {
+		return nil
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(content, response)
+}
+
+// basePath returns the API path the values of kind in target live under
+func basePath(target Target, kind Kind) string {
+	collection := "secrets"
+	if kind == KindVariable {
+		collection = "variables"
+	}
+
+	switch target.Scope {
+	case ScopeEnvironment:
+		return fmt.Sprintf("repos/%s/environments/%s/%s", target.Repository, url.PathEscape(target.Environment), collection)
+	case ScopeOrganization:
+		return fmt.Sprintf("orgs/%s/actions/%s", target.Organization(), collection)
+	default:
+		return fmt.Sprintf("repos/%s/actions/%s", target.Repository, collection)
+	}
+}
+
+func isStatus(err error, status int) bool {
+	var httpErr api.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == status
+}
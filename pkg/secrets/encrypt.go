@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// PublicKey is the key GitHub secrets of a repository, environment or organization are encrypted with
+type PublicKey struct {
+	KeyID string `json:"key_id"`
+	Key   string `json:"key"`
+}
+
+// Encrypt seals value for key as a libsodium sealed box, returning it base64 encoded as the API expects
+func Encrypt(key PublicKey, value string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(key.Key)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key %s: %w", key.KeyID, err)
+	}
+	if len(raw) != 32 {
+		return "", fmt.Errorf("invalid public key %s: expected 32 bytes, got %d", key.KeyID, len(raw))
+	}
+
+	var recipient [32]byte
+	copy(recipient[:], raw)
+
+	sealed, err := box.SealAnonymous(nil, []byte(value), &recipient, rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
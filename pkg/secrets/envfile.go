@@ -0,0 +1,108 @@
+package secrets
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateName checks name against the GitHub naming rules for secrets and variables
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid name '%s', only letters, digits and underscores are allowed and it cannot start with a digit", name)
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+		return fmt.Errorf("invalid name '%s', names cannot start with GITHUB_", name)
+	}
+	return nil
+}
+
+// Pair is a name and value read from an env file
+type Pair struct {
+	Name  string
+	Value string
+}
+
+// ReadEnvFile parses a .env style file: NAME=value lines with optional export prefixes, single or double
+// quoted values and # comments. Later definitions of a name replace earlier ones
+func ReadEnvFile(path string) ([]Pair, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var pairs []Pair
+	index := map[string]int{}
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", path, lineNumber)
+		}
+		name = strings.TrimSpace(name)
+		if err := ValidateName(name); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+
+		if i, seen := index[name]; seen {
+			pairs[i].Value = value
+			continue
+		}
+		index[name] = len(pairs)
+		pairs = append(pairs, Pair{Name: name, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
+
+func parseEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch value[0] {
+	case '"':
+		end := strings.LastIndex(value, `"`)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated double quoted value")
+		}
+		unquoted, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid double quoted value: %w", err)
+		}
+		return unquoted, nil
+	case '\'':
+		end := strings.LastIndex(value, "'")
+		if end == 0 {
+			return "", fmt.Errorf("unterminated single quoted value")
+		}
+		return value[1:end], nil
+	}
+
+	// Unquoted values end at an inline comment
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
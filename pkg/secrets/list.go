@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Listed is a stored secret or variable. Values are never listed
+type Listed struct {
+	Name      string
+	Kind      Kind
+	UpdatedAt time.Time
+}
+
+// List returns the secrets and variables stored in target, sorted by name
+func (c *Client) List(ctx context.Context, target Target) ([]Listed, error) {
+	var listed []Listed
+	for _, kind := range []Kind{KindSecret, KindVariable} {
+		items, err := c.list(ctx, target, kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %ss of %s: %w", kind, target, err)
+		}
+		listed = append(listed, items...)
+	}
+
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].Name < listed[j].Name
+	})
+	return listed, nil
+}
+
+func (c *Client) list(ctx context.Context, target Target, kind Kind) ([]Listed, error) {
+	const perPage = 100
+
+	var listed []Listed
+	for page := 1; ; page++ {
+		var response struct {
+			TotalCount int `json:"total_count"`
+			Secrets    []struct {
+				Name      string    `json:"name"`
+				UpdatedAt time.Time `json:"updated_at"`
+			} `json:"secrets"`
+			Variables []struct {
+				Name      string    `json:"name"`
+				UpdatedAt time.Time `json:"updated_at"`
+			} `json:"variables"`
+		}
+
+		path := fmt.Sprintf("%s?per_page=%d&page=%d", basePath(target, kind), perPage, page)
+		if err := c.send(ctx, http.MethodGet, path, nil, &response); err != nil {
+			return nil, err
+		}
+
+		items := response.Secrets
+		if kind == KindVariable {
+			items = response.Variables
+		}
+		for _, item := range items {
+			listed = append(listed, Listed{Name: item.Name, Kind: kind, UpdatedAt: item.UpdatedAt})
+		}
+
+		if len(items) < perPage || len(listed) >= response.TotalCount {
+			return listed, nil
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package secrets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kind distinguishes encrypted secrets from plain-text configuration variables
+type Kind string
+
+const (
+	KindSecret   Kind = "secret"
+	KindVariable Kind = "variable"
+)
+
+// Scope is where a secret or variable is stored on GitHub
+type Scope string
+
+const (
+	// ScopeRepository stores the value in the repository, available to every workflow
+	ScopeRepository Scope = "repo"
+	// ScopeEnvironment stores the value in a GitHub Environment, available to jobs deploying to it
+	ScopeEnvironment Scope = "environment"
+	// ScopeOrganization stores the value in the organization, shared with the repository
+	ScopeOrganization Scope = "org"
+)
+
+// Scopes lists the valid scopes
+var Scopes = []Scope{ScopeRepository, ScopeEnvironment, ScopeOrganization}
+
+// ParseScope validates s, defaulting to ScopeRepository when empty
+func ParseScope(s string) (Scope, error) {
+	if s == "" {
+		return ScopeRepository, nil
+	}
+	names := make([]string, 0, len(Scopes))
+	for _, scope := range Scopes {
+		if Scope(s) == scope {
+			return scope, nil
+		}
+		names = append(names, string(scope))
+	}
+	return "", fmt.Errorf("unknown scope '%s', expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// Target identifies the repository, environment or organization values are stored in
+type Target struct {
+	Scope Scope
+	// Repository is the owner/name of the repository, which organization values are shared with
+	Repository  string
+	Environment string
+}
+
+// NewTarget validates and returns a target for scope
+func NewTarget(scope Scope, repository string, environment string) (Target, error) {
+	if _, _, ok := strings.Cut(repository, "/"); !ok {
+		return Target{}, fmt.Errorf("invalid repository '%s', expected owner/name", repository)
+	}
+	if scope == ScopeEnvironment && environment == "" {
+		return Target{}, fmt.Errorf("the environment scope needs an environment name")
+	}
+	if scope != ScopeEnvironment {
+		environment = ""
+	}
+	return Target{Scope: scope, Repository: repository, Environment: environment}, nil
+}
+
+// Organization returns the owner of the target repository
+func (t Target) Organization() string {
+	owner, _, _ := strings.Cut(t.Repository, "/")
+	return owner
+}
+
+// String describes the target for logs and reports
+func (t Target) String() string {
+	switch t.Scope {
+	case ScopeEnvironment:
+		return fmt.Sprintf("%s (environment %s)", t.Repository, t.Environment)
+	case ScopeOrganization:
+		return fmt.Sprintf("%s (organization, shared with %s)", t.Organization(), t.Repository)
+	default:
+		return t.Repository
+	}
+}
+
+// Entry is a single value to store
+type Entry struct {
+	Name  string
+	Value string
+	Kind  Kind
+}
+
+// Secret returns a secret entry
+func Secret(name string, value string) Entry {
+	return Entry{Name: name, Value: value, Kind: KindSecret}
+}
+
+// Variable returns a variable entry
+func Variable(name string, value string) Entry {
+	return Entry{Name: name, Value: value, Kind: KindVariable}
+}
+
+// Result is the outcome of storing one entry. It never holds the value
+type Result struct {
+	Name string
+	Kind Kind
+	Err  error
+}
+
+// Report collects the results of storing a batch of entries
+type Report struct {
+	Target  Target
+	Results []Result
+}
+
+// Names returns the sorted names of the entries of kind that were stored
+func (r *Report) Names(kind Kind) []string {
+	var names []string
+	for _, result := range r.Results {
+		if result.Kind == kind && result.Err == nil {
+			names = append(names, result.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Failed returns the results that failed
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing every failed entry, or nil when all were stored
+func (r *Report) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(failed))
+	for _, result := range failed {
+		messages = append(messages, fmt.Sprintf("%s %s: %s", result.Kind, result.Name, result.Err))
+	}
+	return fmt.Errorf("failed to store %d of %d values in %s:\n  %s", len(failed), len(r.Results), r.Target, strings.Join(messages, "\n  "))
+}
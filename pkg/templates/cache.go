@@ -0,0 +1,258 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+)
+
+const (
+	cacheMetadataFile = ".enterprise-cache.json"
+	cacheRefsFile     = "refs.json"
+)
+
+// ErrNotCached is returned by Lookup when no cached tree matches the requested ref
+var ErrNotCached = errors.New("template is not cached")
+
+// Cache stores fetched templates under the user cache directory, keyed by repository and commit
+type Cache struct {
+	dir string
+}
+
+// CacheEntry describes a cached template tree
+type CacheEntry struct {
+	Repository string    `json:"repository"`
+	Commit     string    `json:"commit"`
+	SHA256     string    `json:"sha256"`
+	FetchedAt  time.Time `json:"fetchedAt"`
+	Dir        string    `json:"-"`
+}
+
+// NewCache opens the template cache in the user cache directory
+func NewCache() (*Cache, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate user cache directory: %w", err)
+	}
+	return &Cache{dir: filepath.Join(base, "enterprise-cli", "templates")}, nil
+}
+
+// Dir returns the root directory of the cache
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) repoDir(repository string) string {
+	return filepath.Join(c.dir, strings.ReplaceAll(strings.ToLower(repository), "/", "__"))
+}
+
+// Lookup finds a cached tree for repository at ref, which may be a branch, tag,
+// (abbreviated) commit SHA or empty for the default branch. It returns ErrNotCached
+// when nothing matches, and an error when an abbreviated SHA matches several commits
+func (c *Cache) Lookup(repository, ref string) (*CacheEntry, error) {
+	repoDir := c.repoDir(repository)
+
+	refs := c.readRefs(repository)
+	if commit, ok := refs[refKey(ref)]; ok {
+		if entry, err := c.readEntry(filepath.Join(repoDir, commit)); err == nil {
+			return entry, nil
+		}
+	}
+
+	if !github.IsCommitSHA(ref) {
+		return nil, ErrNotCached
+	}
+	entries, err := os.ReadDir(repoDir)
+	if err != nil {
+		return nil, ErrNotCached
+	}
+	var matches []*CacheEntry
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), ref) {
+			if entry, err := c.readEntry(filepath.Join(repoDir, e.Name())); err == nil {
+				matches = append(matches, entry)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrNotCached
+	case 1:
+		return matches[0], nil
+	}
+	commits := make([]string, 0, len(matches))
+	for _, entry := range matches {
+		commits = append(commits, entry.Commit)
+	}
+	return nil, fmt.Errorf("commit %s is ambiguous in the template cache of %s, it matches %s", ref, repository, strings.Join(commits, ", "))
+}
+
+// Store copies a fetched template into the cache and remembers ref as pointing to its commit
+func (c *Cache) Store(repository, ref string, resolved *Resolved) (*CacheEntry, error) {
+	if resolved.Commit == "" {
+		return nil, fmt.Errorf("cannot cache a template without a commit")
+	}
+
+	repoDir := c.repoDir(repository)
+	entryDir := filepath.Join(repoDir, resolved.Commit)
+
+	entry, err := c.readEntry(entryDir)
+	if err != nil {
+		if err := os.MkdirAll(repoDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		staging, err := os.MkdirTemp(repoDir, ".staging-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache staging directory: %w", err)
+		}
+		defer os.RemoveAll(staging)
+
+		if err := copyTemplateDir(resolved.Dir, staging); err != nil {
+			return nil, fmt.Errorf("failed to copy template into cache: %w", err)
+		}
+
+		entry = &CacheEntry{
+			Repository: repository,
+			Commit:     resolved.Commit,
+			SHA256:     resolved.SHA256,
+			FetchedAt:  time.Now().UTC(),
+			Dir:        entryDir,
+		}
+		data, err := json.MarshalIndent(entry, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(staging, cacheMetadataFile), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write cache metadata: %w", err)
+		}
+		_ = os.RemoveAll(entryDir)
+		if err := os.Rename(staging, entryDir); err != nil {
+			return nil, fmt.Errorf("failed to store template in cache: %w", err)
+		}
+	}
+
+	refs := c.readRefs(repository)
+	refs[refKey(ref)] = resolved.Commit
+	if data, err := json.MarshalIndent(refs, "", "  "); err == nil {
+		_ = os.WriteFile(filepath.Join(repoDir, cacheRefsFile), data, 0644)
+	}
+
+	logging.GetLogger().Debug("Cached template", "repository", repository, "commit", resolved.Commit, "path", entryDir)
+	return entry, nil
+}
+
+// Restore copies a cached tree into dest after checking it still matches its recorded checksum
+func (c *Cache) Restore(entry *CacheEntry, dest string) error {
+	sum, err := HashDir(entry.Dir)
+	if err != nil {
+		return err
+	}
+	if sum != entry.SHA256 {
+		return fmt.Errorf("cached template %s@%s is corrupted: checksum %s does not match %s", entry.Repository, entry.Commit, sum, entry.SHA256)
+	}
+	if err := copyTemplateDir(entry.Dir, dest); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(dest, cacheMetadataFile))
+}
+
+func (c *Cache) readEntry(entryDir string) (*CacheEntry, error) {
+	data, err := os.ReadFile(filepath.Join(entryDir, cacheMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	entry.Dir = entryDir
+	return &entry, nil
+}
+
+func (c *Cache) readRefs(repository string) map[string]string {
+	refs := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(c.repoDir(repository), cacheRefsFile))
+	if err == nil {
+		_ = json.Unmarshal(data, &refs)
+	}
+	return refs
+}
+
+func refKey(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
+
+// HashDir returns a SHA-256 over the relative paths and contents of every file below dir,
+// ignoring git metadata and cache bookkeeping, so identical trees hash identically
+func HashDir(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || d.Name() == cacheMetadataFile {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", dir, err)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, rel := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		info, err := os.Lstat(path)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "%s\x00", rel)
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "link:%s\x00", target)
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%x\x00", fileHash.Sum(nil))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,306 @@
+package templates
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/blazity/enterprise-cli/pkg/logging"
+	"github.com/blazity/enterprise-cli/pkg/runner"
+	"github.com/blazity/enterprise-cli/pkg/utils/filesystem"
+)
+
+// Resolved is a template fetched into Dir. Commit is set when the source is a git repository,
+// and SHA256 is the checksum of the fetched tree as computed by HashDir.
+type Resolved struct {
+	Source    Source
+	Dir       string
+	Commit    string
+	SHA256    string
+	FromCache bool
+}
+
+// FetchOptions controls caching and verification of a fetched template.
+// PinnedCommit and PinnedSHA256, when set, must match the fetched template.
+type FetchOptions struct {
+	Cache        *Cache
+	PinnedCommit string
+	PinnedSHA256 string
+}
+
+// Fetch places the template described by src into the empty directory dest.
+// GitHub templates are cached by commit and served from the cache when the
+// network is unavailable or the requested commit is already cached.
+func Fetch(ctx context.Context, src Source, dest string, opts FetchOptions) (*Resolved, error) {
+	logger := logging.GetLogger()
+	resolved := &Resolved{Source: src, Dir: dest}
+
+	if err := ValidatePinnedCommit(opts.PinnedCommit); err != nil {
+		return nil, err
+	}
+
+	switch src.Kind {
+	case KindGitHub:
+		if err := fetchGitHub(ctx, src, dest, opts, resolved); err != nil {
+			return nil, err
+		}
+
+	case KindLocal:
+		logger.Debug("Copying local template", "path", src.Path)
+		if err := copyTemplateDir(src.Path, dest); err != nil {
+			return nil, fmt.Errorf("failed to copy template from %s: %w", src.Path, err)
+		}
+		if commit, err := github.GetHeadCommit(src.Path); err == nil {
+			resolved.Commit = commit
+		}
+
+	case KindTarball:
+		logger.Debug("Extracting template archive", "path", src.Path)
+		if err := extractTarball(ctx, src.Path, dest); err != nil {
+			return nil, fmt.Errorf("failed to extract template archive %s: %w", src.Path, err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported template source kind %q", src.Kind)
+	}
+
+	sum, err := HashDir(dest)
+	if err != nil {
+		return nil, err
+	}
+	resolved.SHA256 = sum
+
+	if err := Verify(resolved, opts.PinnedCommit, opts.PinnedSHA256); err != nil {
+		return nil, err
+	}
+
+	if src.Kind == KindGitHub && opts.Cache != nil && !resolved.FromCache {
+		if _, err := opts.Cache.Store(src.Repository, src.Ref, resolved); err != nil {
+			logger.Warning("Failed to cache template", "error", err)
+		}
+	}
+
+	logger.Debug("Fetched template", "source", src.String(), "commit", resolved.Commit, "sha256", resolved.SHA256, "cached", resolved.FromCache)
+	return resolved, nil
+}
+
+// ValidatePinnedCommit checks that a pinned commit is a full SHA or one abbreviated to at least 7 characters
+func ValidatePinnedCommit(pinnedCommit string) error {
+	if pinnedCommit != "" && !github.IsCommitSHA(strings.ToLower(pinnedCommit)) {
+		return fmt.Errorf("pinned commit %q must be a commit SHA of 7 to 40 hexadecimal characters", pinnedCommit)
+	}
+	return nil
+}
+
+// Verify checks a resolved template against a pinned commit (full or abbreviated) and checksum
+func Verify(resolved *Resolved, pinnedCommit, pinnedSHA256 string) error {
+	if err := ValidatePinnedCommit(pinnedCommit); err != nil {
+		return err
+	}
+	if pinnedCommit != "" && !strings.HasPrefix(resolved.Commit, strings.ToLower(pinnedCommit)) {
+		return fmt.Errorf("template commit %q does not match the pinned commit %q", resolved.Commit, pinnedCommit)
+	}
+	if pinnedSHA256 != "" && !strings.EqualFold(resolved.SHA256, pinnedSHA256) {
+		return fmt.Errorf("template checksum %s does not match the pinned sha256 %s", resolved.SHA256, pinnedSHA256)
+	}
+	return nil
+}
+
+func fetchGitHub(ctx context.Context, src Source, dest string, opts FetchOptions, resolved *Resolved) error {
+	logger := logging.GetLogger()
+
+	// A full commit SHA is immutable, so a cached copy can be used without the network
+	if opts.Cache != nil && len(src.Ref) == 40 && github.IsCommitSHA(src.Ref) {
+		if entry, err := opts.Cache.Lookup(src.Repository, src.Ref); err == nil {
+			if err := opts.Cache.Restore(entry, dest); err != nil {
+				return err
+			}
+			logger.Debug("Using cached template", "repository", src.Repository, "commit", entry.Commit)
+			resolved.Commit = entry.Commit
+			resolved.FromCache = true
+			return nil
+		}
+	}
+
+	cloneOpts := github.CloneOptions{
+		Repository:  src.Repository,
+		Branch:      src.Ref,
+		Destination: dest,
+		Depth:       1,
+	}
+	cloneErr := github.CloneRepository(ctx, cloneOpts)
+	if cloneErr == nil {
+		commit, err := github.GetHeadCommit(dest)
+		if err != nil {
+			return err
+		}
+		resolved.Commit = commit
+		return nil
+	}
+
+	// Only an unreachable GitHub falls back to the cache, a cancelled clone, a misspelled repository,
+	// a deleted branch or failed authentication must not silently use an old copy
+	offline := errors.Is(cloneErr, github.ErrNetwork) || runner.Get().Offline()
+	if opts.Cache == nil || ctx.Err() != nil || !offline {
+		return fmt.Errorf("failed to clone template %s: %w", src, cloneErr)
+	}
+	entry, err := opts.Cache.Lookup(src.Repository, src.Ref)
+	if errors.Is(err, ErrNotCached) {
+		return fmt.Errorf("failed to clone template %s and no cached copy is available: %w", src, cloneErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clone template %s and the cached copies cannot be used: %w", src, err)
+	}
+
+	logger.Warning("Could not fetch the template, using the cached copy", "source", src.String(), "commit", entry.Commit, "fetchedAt", entry.FetchedAt.Format("2006-01-02 15:04"))
+	if err := clearDir(dest); err != nil {
+		return err
+	}
+	if err := opts.Cache.Restore(entry, dest); err != nil {
+		return err
+	}
+	resolved.Commit = entry.Commit
+	resolved.FromCache = true
+	return nil
+}
+
+// clearDir removes everything inside dir, keeping dir itself
+func clearDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyTemplateDir copies a local template, leaving out its git metadata
+func copyTemplateDir(src, dest string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		srcPath := filepath.Join(src, entry.Name())
+		destPath := filepath.Join(dest, entry.Name())
+		if entry.IsDir() {
+			err = filesystem.CopyDir(srcPath, destPath)
+		} else {
+			err = filesystem.CopyFile(srcPath, destPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTarball unpacks a .tar.gz from a path or URL into dest. When every entry
+// shares a single top-level directory, as in GitHub archives, that directory is stripped.
+func extractTarball(ctx context.Context, path, dest string) error {
+	reader, err := openTarball(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	staging, err := os.MkdirTemp("", "enterprise-template-archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		target := filepath.Join(staging, header.Name)
+		if !strings.HasPrefix(target, filepath.Clean(staging)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q escapes the destination", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		}
+	}
+
+	root := staging
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(staging, entries[0].Name())
+	}
+
+	return copyTemplateDir(root, dest)
+}
+
+func openTarball(ctx context.Context, path string) (io.ReadCloser, error) {
+	if !isURL(path) {
+		return os.Open(path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The runner carries the download, so it is recorded and replayed along with the API traffic
+	client := &http.Client{Transport: runner.Get().Transport(http.DefaultTransport)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", path, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,119 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultRepository is the template used when no --template is given
+const DefaultRepository = "blazity/next-enterprise-terraform"
+
+// Kind is the type of location a template is fetched from
+type Kind string
+
+const (
+	KindGitHub  Kind = "github"
+	KindLocal   Kind = "local"
+	KindTarball Kind = "tarball"
+)
+
+// Source describes where a template comes from. Repository and Ref are set for
+// GitHub sources, Path for local directories and tarballs (which may be URLs).
+type Source struct {
+	Kind       Kind
+	Repository string
+	Ref        string
+	Path       string
+}
+
+var repositoryPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// DefaultSource returns the default branch of the default template repository
+func DefaultSource() Source {
+	return Source{Kind: KindGitHub, Repository: DefaultRepository}
+}
+
+// ParseSource parses a --template value: "owner/repo", "owner/repo@ref",
+// a local directory, or a local path or URL of a .tar.gz archive
+func ParseSource(value string) (Source, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultSource(), nil
+	}
+
+	if strings.HasSuffix(value, ".tar.gz") || strings.HasSuffix(value, ".tgz") {
+		if isURL(value) {
+			return Source{Kind: KindTarball, Path: value}, nil
+		}
+		path, err := expandPath(value)
+		if err != nil {
+			return Source{}, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return Source{}, fmt.Errorf("template archive not found: %s", value)
+		}
+		return Source{Kind: KindTarball, Path: path}, nil
+	}
+
+	if looksLikePath(value) {
+		path, err := expandPath(value)
+		if err != nil {
+			return Source{}, err
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			return Source{}, fmt.Errorf("template directory not found: %s", value)
+		}
+		return Source{Kind: KindLocal, Path: path}, nil
+	}
+
+	repo, ref, _ := strings.Cut(value, "@")
+	repo = strings.TrimSuffix(strings.TrimPrefix(repo, "https://github.com/"), ".git")
+	if !repositoryPattern.MatchString(repo) {
+		return Source{}, fmt.Errorf("invalid template %q, expected owner/repo[@ref], a directory or a .tar.gz archive", value)
+	}
+	if strings.Contains(value, "@") && ref == "" {
+		return Source{}, fmt.Errorf("invalid template %q, missing ref after '@'", value)
+	}
+
+	return Source{Kind: KindGitHub, Repository: repo, Ref: ref}, nil
+}
+
+// String returns the source in the form accepted by ParseSource
+func (s Source) String() string {
+	switch s.Kind {
+	case KindGitHub:
+		if s.Ref != "" {
+			return s.Repository + "@" + s.Ref
+		}
+		return s.Repository
+	default:
+		return s.Path
+	}
+}
+
+func isURL(value string) bool {
+	return strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://")
+}
+
+func looksLikePath(value string) bool {
+	if strings.HasPrefix(value, ".") || strings.HasPrefix(value, "/") || strings.HasPrefix(value, "~") || filepath.IsAbs(value) {
+		return true
+	}
+	info, err := os.Stat(value)
+	return err == nil && info.IsDir()
+}
+
+func expandPath(value string) (string, error) {
+	if strings.HasPrefix(value, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		value = filepath.Join(home, value[2:])
+	}
+	return filepath.Abs(value)
+}
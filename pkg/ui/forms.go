@@ -3,6 +3,7 @@ package ui
 import (
 	"context"
 	"errors"
+	"os"
 
 	"github.com/blazity/enterprise-cli/pkg/logging"
 	"github.com/charmbracelet/bubbles/help"
@@ -112,9 +113,17 @@ func (m FormModel) View() string {
 }
 
 func RunForm(form *huh.Form, cancel context.CancelFunc) error {
+	resume := pauseActiveProgress()
+	defer resume()
+
 	model := NewFormModel(form, cancel)
 
-	program := tea.NewProgram(model)
+	var opts []tea.ProgramOption
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		// Stdout is piped, e.g. to read the result printed with --output, so the form is shown on the terminal behind stderr
+		opts = append(opts, tea.WithOutput(os.Stderr))
+	}
+	program := tea.NewProgram(model, opts...)
 
 	finalModel, err := program.Run()
 
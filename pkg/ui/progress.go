@@ -0,0 +1,348 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type stepState int
+
+const (
+	stepPending stepState = iota
+	stepRunning
+	stepSucceeded
+	stepFailed
+	stepCancelled
+	stepSkipped
+)
+
+type progressStep struct {
+	name     string
+	state    stepState
+	started  time.Time
+	duration time.Duration
+	err      string
+}
+
+// Progress shows the ordered steps of a long operation with a spinner and the elapsed time of the running
+// step. On a terminal it is a live view, elsewhere every finished step is printed as a plain line
+type Progress struct {
+	title string
+	out   io.Writer
+	plain bool
+
+	mu      sync.Mutex
+	steps   []progressStep
+	program *tea.Program
+	done    chan struct{}
+	stopped bool
+}
+
+var (
+	activeMu       sync.Mutex
+	activeProgress *Progress
+)
+
+// IsTerminal reports whether w is a terminal, so a live view can be drawn on it
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// NewProgress returns a progress view written to out, listing the planned steps as pending. Steps started
+// without being planned are added when they start. plain prints finished steps as lines instead of a live view
+func NewProgress(title string, out io.Writer, planned []string, plain bool) *Progress {
+	p := &Progress{title: title, out: out, plain: plain}
+	for _, name := range planned {
+		p.steps = append(p.steps, progressStep{name: name})
+	}
+	return p
+}
+
+// Start shows the view. Forms run with RunForm hide it while they are shown
+func (p *Progress) Start() {
+	activeMu.Lock()
+	activeProgress = p
+	activeMu.Unlock()
+
+	if p.plain {
+		fmt.Fprintln(p.out, p.title)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.startProgram()
+}
+
+// Stop leaves the final state of the steps on screen. Steps that never ran are marked as skipped
+// when the operation succeeded
+func (p *Progress) Stop(succeeded bool) {
+	activeMu.Lock()
+	if activeProgress == p {
+		activeProgress = nil
+	}
+	activeMu.Unlock()
+
+	p.mu.Lock()
+	p.stopped = true
+	for i := range p.steps {
+		if p.steps[i].state == stepPending && succeeded {
+			p.steps[i].state = stepSkipped
+		}
+	}
+	p.mu.Unlock()
+
+	p.stopProgram(false)
+}
+
+// Plain reports whether steps are printed as plain lines rather than in a live view
+func (p *Progress) Plain() bool {
+	return p.plain
+}
+
+// Writer returns a writer printing log lines above the live view, or to fallback while it is not shown
+func (p *Progress) Writer(fallback io.Writer) io.Writer {
+	return progressWriter{progress: p, fallback: fallback}
+}
+
+// StepStarted marks the step called name as running
+func (p *Progress) StepStarted(name string) {
+	p.mu.Lock()
+	i := p.find(name)
+	if i < 0 {
+		// Unplanned steps run after the last step that started
+		i = 0
+		for j, step := range p.steps {
+			if step.state != stepPending {
+				i = j + 1
+			}
+		}
+		p.steps = append(p.steps[:i], append([]progressStep{{name: name}}, p.steps[i:]...)...)
+	}
+	p.steps[i].state = stepRunning
+	p.steps[i].started = time.Now()
+	program := p.program
+	p.mu.Unlock()
+
+	if program != nil {
+		program.Send(progressUpdateMsg{})
+	}
+}
+
+// StepFinished marks the step called name as finished with status ok, failed or cancelled
+func (p *Progress) StepFinished(name string, status string, duration time.Duration, err error) {
+	p.mu.Lock()
+	i := p.find(name)
+	if i < 0 {
+		p.mu.Unlock()
+		return
+	}
+	step := &p.steps[i]
+	step.duration = duration
+	switch status {
+	case "ok":
+		step.state = stepSucceeded
+	case "cancelled":
+		step.state = stepCancelled
+	default:
+		step.state = stepFailed
+	}
+	if err != nil {
+		step.err = err.Error()
+	}
+	line := p.renderStep(*step, "")
+	program := p.program
+	p.mu.Unlock()
+
+	if p.plain {
+		fmt.Fprintln(p.out, line)
+		return
+	}
+	if program != nil {
+		program.Send(progressUpdateMsg{})
+	}
+}
+
+// find returns the index of the first unfinished step called name, or -1
+func (p *Progress) find(name string) int {
+	for i, step := range p.steps {
+		if step.name == name && (step.state == stepPending || step.state == stepRunning) {
+			return i
+		}
+	}
+	return -1
+}
+
+// startProgram runs the live view. It must be called with p.mu held
+func (p *Progress) startProgram() {
+	program := tea.NewProgram(
+		progressModel{progress: p, spinner: spinner.New(spinner.WithSpinner(spinner.MiniDot), spinner.WithStyle(runningStyle))},
+		tea.WithOutput(p.out),
+		// The view takes no input, so Ctrl+C reaches the signal handler that cancels the operation
+		tea.WithInput(nil),
+		tea.WithoutSignalHandler(),
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = program.Run()
+	}()
+	p.program, p.done = program, done
+}
+
+// stopProgram quits the live view, erasing it when hide is set
+func (p *Progress) stopProgram(hide bool) bool {
+	p.mu.Lock()
+	program, done := p.program, p.done
+	p.program, p.done = nil, nil
+	p.mu.Unlock()
+
+	if program == nil {
+		return false
+	}
+	program.Send(progressQuitMsg{hide: hide})
+	<-done
+	return true
+}
+
+// pause hides the live view while a form is shown
+func (p *Progress) pause() bool {
+	return p.stopProgram(true)
+}
+
+func (p *Progress) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.stopped && p.program == nil {
+		p.startProgram()
+	}
+}
+
+// pauseActiveProgress hides the progress view in use, returning the function showing it again
+func pauseActiveProgress() func() {
+	activeMu.Lock()
+	p := activeProgress
+	activeMu.Unlock()
+
+	if p == nil || !p.pause() {
+		return func() {}
+	}
+	return p.resume
+}
+
+func (p *Progress) renderStep(step progressStep, spinnerView string) string {
+	var icon, detail string
+	switch step.state {
+	case stepPending:
+		icon = faintStyle.Render("·")
+	case stepRunning:
+		icon = spinnerView
+		detail = formatElapsed(time.Since(step.started))
+	case stepSucceeded:
+		icon = Success("✓")
+		detail = formatElapsed(step.duration)
+	case stepFailed:
+		icon = Error("✗")
+		detail = formatElapsed(step.duration)
+	case stepCancelled:
+		icon = HighlightStyle.Render("✗")
+		detail = "cancelled"
+	case stepSkipped:
+		icon = faintStyle.Render("-")
+		detail = "skipped"
+	}
+	name := step.name
+	if step.state == stepPending || step.state == stepSkipped {
+		name = faintStyle.Render(name)
+	}
+	line := fmt.Sprintf("%s %s", icon, name)
+	if detail != "" {
+		line += " " + faintStyle.Render(detail)
+	}
+	if step.err != "" && step.state == stepFailed {
+		line += "\n    " + ErrorStyle.Render(step.err)
+	}
+	return line
+}
+
+// formatElapsed renders d with a precision suiting how long steps take
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Truncate(time.Second).String()
+}
+
+type progressUpdateMsg struct{}
+
+type progressQuitMsg struct {
+	hide bool
+}
+
+type progressModel struct {
+	progress *Progress
+	spinner  spinner.Model
+	hidden   bool
+}
+
+func (m progressModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case progressQuitMsg:
+		m.hidden = msg.hide
+		return m, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m progressModel) View() string {
+	if m.hidden {
+		return ""
+	}
+
+	p := m.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lines := []string{SubHeader(p.title)}
+	for _, step := range p.steps {
+		lines = append(lines, "  "+strings.ReplaceAll(p.renderStep(step, m.spinner.View()), "\n", "\n  "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// progressWriter prints whole log lines above the live view
+type progressWriter struct {
+	progress *Progress
+	fallback io.Writer
+}
+
+func (w progressWriter) Write(b []byte) (int, error) {
+	w.progress.mu.Lock()
+	program := w.progress.program
+	w.progress.mu.Unlock()
+
+	if program == nil {
+		return w.fallback.Write(b)
+	}
+	program.Println(strings.TrimRight(string(b), "\n"))
+	return len(b), nil
+}
@@ -0,0 +1,559 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blazity/enterprise-cli/pkg/github"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RunsBackend fetches and acts on the workflow runs shown by the runs dashboard
+type RunsBackend interface {
+	Repository() string
+	Runs() ([]github.WorkflowRun, error)
+	Jobs(runID string) ([]github.WorkflowJob, error)
+	Cancel(runID string) error
+	RerunFailed(runID string) error
+	Logs(run github.WorkflowRun) (string, error)
+	Diagnose(run github.WorkflowRun) (string, error)
+	Open(runID string) error
+	RateLimit() (github.RateLimit, error)
+}
+
+const (
+	// activePollInterval is used while a run is queued or in progress
+	activePollInterval = 5 * time.Second
+	// idlePollInterval is used once every run has completed
+	idlePollInterval = 30 * time.Second
+	// maxPollInterval caps the backoff after failed polls
+	maxPollInterval = 2 * time.Minute
+	// rateLimitReserve is the number of requests left to other tools before polling pauses
+	rateLimitReserve = 50
+)
+
+var (
+	faintStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#7F8C8D"))
+	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#F39C12")).Bold(true)
+	runningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#3498DB"))
+)
+
+type runsKeyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	Cancel   key.Binding
+	Rerun    key.Binding
+	Logs     key.Binding
+	Diagnose key.Binding
+	Open     key.Binding
+	Refresh  key.Binding
+	Back     key.Binding
+	Quit     key.Binding
+}
+
+var runsKeys = runsKeyMap{
+	Up:       key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:     key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Cancel:   key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "cancel run")),
+	Rerun:    key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "re-run failed jobs")),
+	Logs:     key.NewBinding(key.WithKeys("l", "enter"), key.WithHelp("l", "logs")),
+	Diagnose: key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "diagnose")),
+	Open:     key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open in browser")),
+	Refresh:  key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "refresh")),
+	Back:     key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "back")),
+	Quit:     key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+}
+
+type runsMsg struct {
+	generation int
+	runs       []github.WorkflowRun
+	limit      github.RateLimit
+	err        error
+}
+
+type jobsMsg struct {
+	runID string
+	jobs  []github.WorkflowJob
+	err   error
+}
+
+type pollMsg struct {
+	generation int
+}
+
+type actionMsg struct {
+	text string
+	err  error
+}
+
+type logsMsg struct {
+	runID   string
+	content string
+	err     error
+}
+
+// RunsModel is a live dashboard of the workflow runs of a repository
+type RunsModel struct {
+	backend RunsBackend
+
+	runs     []github.WorkflowRun
+	jobs     map[string][]github.WorkflowJob
+	cursor   int
+	limit    github.RateLimit
+	updated  time.Time
+	nextPoll time.Time
+	failures int
+	err      error
+	status   string
+
+	// generation invalidates scheduled polls when a refresh is forced
+	generation    int
+	confirmCancel string
+
+	showLogs  bool
+	logsRun   string
+	logsTitle string
+	// followLogs scrolls to the end of logs, while diagnoses are read from the top
+	followLogs bool
+	logs       viewport.Model
+
+	width  int
+	height int
+}
+
+// NewRunsModel returns a dashboard polling backend
+func NewRunsModel(backend RunsBackend) RunsModel {
+	return RunsModel{
+		backend: backend,
+		jobs:    map[string][]github.WorkflowJob{},
+		logs:    viewport.New(80, 20),
+	}
+}
+
+func (m RunsModel) Init() tea.Cmd {
+	return m.fetchRuns()
+}
+
+func (m RunsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.logs.Width = msg.Width
+		m.logs.Height = max(msg.Height-4, 5)
+		return m, nil
+
+	case runsMsg:
+		if msg.generation != m.generation {
+			return m, nil
+		}
+		m.err = msg.err
+		if msg.err != nil {
+			m.failures++
+		} else {
+			m.failures = 0
+			m.runs = msg.runs
+			m.updated = time.Now()
+			if m.cursor >= len(m.runs) {
+				m.cursor = max(len(m.runs)-1, 0)
+			}
+		}
+		if msg.limit.Limit > 0 {
+			m.limit = msg.limit
+		}
+
+		delay := m.pollDelay(time.Now())
+		m.nextPoll = time.Now().Add(delay)
+		generation := m.generation
+		cmds := []tea.Cmd{tea.Tick(delay, func(time.Time) tea.Msg { return pollMsg{generation: generation} })}
+		if run, ok := m.selected(); ok {
+			cmds = append(cmds, m.fetchJobs(run.ID))
+		}
+		return m, tea.Batch(cmds...)
+
+	case pollMsg:
+		if msg.generation != m.generation {
+			return m, nil
+		}
+		return m, m.fetchRuns()
+
+	case jobsMsg:
+		if msg.err == nil {
+			m.jobs[msg.runID] = msg.jobs
+		}
+		return m, nil
+
+	case actionMsg:
+		if msg.err != nil {
+			m.status = Error(msg.err.Error())
+		} else {
+			m.status = Success(msg.text)
+		}
+		m.generation++
+		return m, m.fetchRuns()
+
+	case logsMsg:
+		if msg.runID != m.logsRun {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.logs.SetContent(Error(msg.err.Error()))
+		} else if strings.TrimSpace(msg.content) == "" {
+			m.logs.SetContent(faintStyle.Render("No logs available yet"))
+		} else {
+			m.logs.SetContent(msg.content)
+		}
+		if m.followLogs {
+			m.logs.GotoBottom()
+		} else {
+			m.logs.GotoTop()
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.showLogs {
+			return m.updateLogs(msg)
+		}
+		return m.updateList(msg)
+	}
+
+	return m, nil
+}
+
+func (m RunsModel) updateLogs(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if key.Matches(msg, runsKeys.Back) {
+		m.showLogs = false
+		m.logsRun = ""
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.logs, cmd = m.logs.Update(msg)
+	return m, cmd
+}
+
+func (m RunsModel) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pendingCancel := m.confirmCancel
+	m.confirmCancel = ""
+
+	switch {
+	case key.Matches(msg, runsKeys.Quit):
+		return m, tea.Quit
+
+	case key.Matches(msg, runsKeys.Up):
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m, m.fetchSelectedJobs()
+
+	case key.Matches(msg, runsKeys.Down):
+		if m.cursor < len(m.runs)-1 {
+			m.cursor++
+		}
+		return m, m.fetchSelectedJobs()
+
+	case key.Matches(msg, runsKeys.Refresh):
+		m.generation++
+		m.status = ""
+		return m, m.fetchRuns()
+	}
+
+	run, ok := m.selected()
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, runsKeys.Cancel):
+		if run.Status == "completed" {
+			m.status = faintStyle.Render("The run has already completed")
+			return m, nil
+		}
+		if pendingCancel != run.ID {
+			m.confirmCancel = run.ID
+			m.status = Highlight(fmt.Sprintf("Press c again to cancel run %s", run.ID))
+			return m, nil
+		}
+		m.status = "Cancelling..."
+		return m, m.action(fmt.Sprintf("Requested cancellation of run %s", run.ID), func() error {
+			return m.backend.Cancel(run.ID)
+		})
+
+	case key.Matches(msg, runsKeys.Rerun):
+		if run.Status != "completed" || isSuccessful(run.Conclusion) {
+			m.status = faintStyle.Render("Only completed runs with failed jobs can be re-run")
+			return m, nil
+		}
+		m.status = "Re-running..."
+		return m, m.action(fmt.Sprintf("Re-running the failed jobs of run %s", run.ID), func() error {
+			return m.backend.RerunFailed(run.ID)
+		})
+
+	case key.Matches(msg, runsKeys.Logs):
+		m.showLogs = true
+		m.logsRun = run.ID
+		m.logsTitle = fmt.Sprintf("Logs of run %s", run.ID)
+		m.followLogs = true
+		m.logs.SetContent(faintStyle.Render("Downloading logs..."))
+		backend := m.backend
+		return m, func() tea.Msg {
+			content, err := backend.Logs(run)
+			return logsMsg{runID: run.ID, content: content, err: err}
+		}
+
+	case key.Matches(msg, runsKeys.Diagnose):
+		if run.Status != "completed" || run.Conclusion != "failure" {
+			m.status = faintStyle.Render("Only failed runs can be diagnosed")
+			return m, nil
+		}
+		m.showLogs = true
+		m.logsRun = run.ID
+		m.logsTitle = fmt.Sprintf("Diagnosis of run %s", run.ID)
+		m.followLogs = false
+		m.logs.SetContent(faintStyle.Render("Analysing the logs of the failed jobs..."))
+		backend := m.backend
+		return m, func() tea.Msg {
+			content, err := backend.Diagnose(run)
+			return logsMsg{runID: run.ID, content: content, err: err}
+		}
+
+	case key.Matches(msg, runsKeys.Open):
+		backend := m.backend
+		return m, func() tea.Msg {
+			if err := backend.Open(run.ID); err != nil {
+				return actionMsg{err: err}
+			}
+			return actionMsg{text: "Opened the run in the browser"}
+		}
+	}
+
+	return m, nil
+}
+
+// pollDelay polls quickly while runs are active, backs off exponentially after failures and
+// waits for the rate limit to reset when few requests are left
+func (m RunsModel) pollDelay(now time.Time) time.Duration {
+	delay := idlePollInterval
+	for _, run := range m.runs {
+		if run.Status != "completed" {
+			delay = activePollInterval
+			break
+		}
+	}
+
+	for i := 0; i < m.failures; i++ {
+		delay *= 2
+		if delay >= maxPollInterval {
+			delay = maxPollInterval
+			break
+		}
+	}
+
+	if m.limit.Limit > 0 && m.limit.Remaining < rateLimitReserve && m.limit.Reset.After(now) {
+		delay = max(delay, m.limit.Reset.Sub(now)+time.Second)
+	}
+	return delay
+}
+
+func (m RunsModel) fetchRuns() tea.Cmd {
+	backend := m.backend
+	generation := m.generation
+	return func() tea.Msg {
+		limit, _ := backend.RateLimit()
+		if limit.Limit > 0 && limit.Remaining < rateLimitReserve {
+			return runsMsg{generation: generation, limit: limit, err: fmt.Errorf("rate limit nearly exhausted, pausing until %s", limit.Reset.Local().Format("15:04:05"))}
+		}
+		runs, err := backend.Runs()
+		return runsMsg{generation: generation, runs: runs, limit: limit, err: err}
+	}
+}
+
+func (m RunsModel) fetchJobs(runID string) tea.Cmd {
+	backend := m.backend
+	return func() tea.Msg {
+		jobs, err := backend.Jobs(runID)
+		return jobsMsg{runID: runID, jobs: jobs, err: err}
+	}
+}
+
+func (m RunsModel) fetchSelectedJobs() tea.Cmd {
+	run, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	if _, cached := m.jobs[run.ID]; cached && run.Status == "completed" {
+		return nil
+	}
+	return m.fetchJobs(run.ID)
+}
+
+func (m RunsModel) action(text string, fn func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := fn(); err != nil {
+			return actionMsg{err: err}
+		}
+		return actionMsg{text: text}
+	}
+}
+
+func (m RunsModel) selected() (github.WorkflowRun, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.runs) {
+		return github.WorkflowRun{}, false
+	}
+	return m.runs[m.cursor], true
+}
+
+func (m RunsModel) View() string {
+	if m.showLogs {
+		return m.viewLogs()
+	}
+
+	var b strings.Builder
+	b.WriteString(SubHeader("Workflow runs of "+m.backend.Repository()) + "\n\n")
+
+	if len(m.runs) == 0 {
+		if m.updated.IsZero() && m.err == nil {
+			b.WriteString(faintStyle.Render("Loading workflow runs...") + "\n")
+		} else {
+			b.WriteString(faintStyle.Render("No workflow runs yet") + "\n")
+		}
+	}
+
+	for i, run := range m.runs {
+		title := run.Title
+		if title == "" {
+			title = run.Name
+		}
+		line := fmt.Sprintf("%s %-24s %-40s %-16s %s", RunIcon(run.Status, run.Conclusion), truncate(run.Name, 24), truncate(title, 40), truncate(run.Branch, 16), relativeTime(run.CreatedAt))
+		if i == m.cursor {
+			b.WriteString(selectedStyle.Render("› ") + line + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	if run, ok := m.selected(); ok {
+		b.WriteString("\n" + SubHeader(fmt.Sprintf("Run %s · %s", run.ID, runState(run))) + "\n")
+		jobs, loaded := m.jobs[run.ID]
+		if !loaded {
+			b.WriteString(faintStyle.Render("  Loading jobs...") + "\n")
+		}
+		for _, job := range jobs {
+			b.WriteString(fmt.Sprintf("  %s %s %s\n", RunIcon(job.Status, job.Conclusion), job.Name, faintStyle.Render(duration(job.StartedAt, job.CompletedAt))))
+			for _, step := range job.Steps {
+				b.WriteString(fmt.Sprintf("      %s %s %s\n", RunIcon(step.Status, step.Conclusion), step.Name, faintStyle.Render(duration(step.StartedAt, step.CompletedAt))))
+			}
+		}
+	}
+
+	b.WriteString("\n")
+	if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	if m.err != nil {
+		b.WriteString(Error(m.err.Error()) + "\n")
+	}
+	b.WriteString(faintStyle.Render(m.footer()) + "\n")
+
+	return b.String()
+}
+
+func (m RunsModel) viewLogs() string {
+	header := SubHeader(m.logsTitle)
+	help := faintStyle.Render("↑/↓ scroll · esc back")
+	return header + "\n" + m.logs.View() + "\n" + help
+}
+
+func (m RunsModel) footer() string {
+	parts := []string{}
+	if !m.updated.IsZero() {
+		parts = append(parts, "updated "+m.updated.Format("15:04:05"))
+	}
+	if !m.nextPoll.IsZero() {
+		parts = append(parts, "next refresh "+m.nextPoll.Format("15:04:05"))
+	}
+	if m.limit.Limit > 0 {
+		parts = append(parts, fmt.Sprintf("API %d/%d", m.limit.Remaining, m.limit.Limit))
+	}
+
+	keys := []key.Binding{runsKeys.Up, runsKeys.Down, runsKeys.Cancel, runsKeys.Rerun, runsKeys.Logs, runsKeys.Diagnose, runsKeys.Open, runsKeys.Refresh, runsKeys.Quit}
+	help := make([]string, 0, len(keys))
+	for _, k := range keys {
+		help = append(help, k.Help().Key+" "+k.Help().Desc)
+	}
+
+	return strings.Join(parts, " · ") + "\n" + strings.Join(help, " · ")
+}
+
+// RunIcon returns the marker of a run, job or step status
+func RunIcon(status string, conclusion string) string {
+	switch status {
+	case "completed":
+		switch conclusion {
+		case "success":
+			return Success("✓")
+		case "skipped", "neutral":
+			return faintStyle.Render("-")
+		case "cancelled":
+			return faintStyle.Render("⊘")
+		default:
+			return Error("✗")
+		}
+	case "in_progress":
+		return runningStyle.Render("●")
+	default:
+		return faintStyle.Render("○")
+	}
+}
+
+func runState(run github.WorkflowRun) string {
+	if run.Status == "completed" {
+		return run.Conclusion
+	}
+	return strings.ReplaceAll(run.Status, "_", " ")
+}
+
+func isSuccessful(conclusion string) bool {
+	return conclusion == "success" || conclusion == "skipped" || conclusion == "neutral"
+}
+
+func duration(start time.Time, end time.Time) string {
+	if start.IsZero() {
+		return ""
+	}
+	if end.IsZero() || end.Before(start) {
+		end = time.Now()
+	}
+	return end.Sub(start).Round(time.Second).String()
+}
+
+func relativeTime(timestamp string) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp
+	}
+	since := time.Since(t)
+	switch {
+	case since < time.Minute:
+		return "just now"
+	case since < time.Hour:
+		return fmt.Sprintf("%dm ago", int(since.Minutes()))
+	case since < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(since.Hours()))
+	default:
+		return t.Local().Format("2006-01-02")
+	}
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var summaryBoxStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("#2ECC71")).
+	Padding(0, 1)
+
+// SummaryField is a labelled value of a Summary
+type SummaryField struct {
+	Label string
+	Value string
+}
+
+// Summary is shown once an operation finished, with what it produced and what is left to do
+type Summary struct {
+	Title     string
+	Fields    []SummaryField
+	NextSteps []string
+	Warnings  []string
+}
+
+// Render returns the summary in a box, or as plain lines when plain is set
+func (s Summary) Render(plain bool) string {
+	var lines []string
+	if plain {
+		lines = append(lines, s.Title)
+	} else {
+		lines = append(lines, Success(s.Title))
+	}
+
+	width := 0
+	for _, field := range s.Fields {
+		if field.Value != "" && len(field.Label) > width {
+			width = len(field.Label)
+		}
+	}
+	for _, field := range s.Fields {
+		if field.Value == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-*s %s", width+1, field.Label+":", field.Value))
+	}
+
+	if len(s.Warnings) > 0 {
+		lines = append(lines, "", summaryHeading("Warnings", plain))
+		for _, warning := range s.Warnings {
+			lines = append(lines, "  ! "+warning)
+		}
+	}
+
+	if len(s.NextSteps) > 0 {
+		lines = append(lines, "", summaryHeading("Next steps", plain))
+		for i, step := range s.NextSteps {
+			lines = append(lines, fmt.Sprintf("  %d. %s", i+1, step))
+		}
+	}
+
+	content := strings.Join(lines, "\n")
+	if plain {
+		return content + "\n"
+	}
+	return summaryBoxStyle.Render(content) + "\n"
+}
+
+func summaryHeading(text string, plain bool) string {
+	if plain {
+		return text + ":"
+	}
+	return SubHeader(text)
+}
@@ -0,0 +1,13 @@
+package version
+
+// Version, Commit and BuildTime are set at build time through -ldflags -X
+var (
+	Version   = "dev"
+	Commit    = "none"
+	BuildTime = "unknown"
+)
+
+// String returns a one-line description of the build
+func String() string {
+	return Version + " (commit " + Commit + ", built " + BuildTime + ")"
+}